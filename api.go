@@ -4,6 +4,8 @@ import (
 	"io"
 	"github.com/batchcorp/thrift-iterator/spi"
 	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
 )
 
 type Protocol int
@@ -11,10 +13,34 @@ type Protocol int
 var ProtocolBinary Protocol = 1
 var ProtocolCompact Protocol = 2
 
+// Presence embedded in struct tracks which fields are set, see IsSet and Unset
+type Presence = protocol.Presence
+
 type Config struct {
 	Protocol      Protocol
 	StaticCodegen bool
 	Extensions    spi.Extensions
+	// OmitPolicy decides which struct fields to skip when encoding, fields tagged omitempty are skipped when empty anyway
+	OmitPolicy spi.OmitPolicy
+	// CompactVersion is written in the message header of compact protocol, protocol.COMPACT_VERSION if not set.
+	// protocol.COMPACT_VERSON_BE encodes double as big endian, the iterator follows the version of message header read
+	CompactVersion byte
+	// ZeroCopy makes []byte decoded from buffer by Unmarshal or NewDecoder alias the buffer instead of copying.
+	// The buffer must outlive the decoded values and must not be modified while they are in use.
+	// Values decoded from io.Reader are always copied
+	ZeroCopy bool
+	// ZeroCopyString also makes string alias the buffer through unsafe, same contract as ZeroCopy applies,
+	// modifying the buffer breaks the immutability of string
+	ZeroCopyString bool
+	// MaxDepth limits the nesting of structs when decoding and encoding, protocol.DefaultMaxDepth if not set.
+	// The containers skipped or decoded as general values are counted as well. Negative means no limit
+	MaxDepth int
+	// ReuseValues makes decoding into an existing value reuse its memory: non-nil pointers are decoded into,
+	// slices are truncated keeping their capacity and maps are cleared. Fields missing from the message keep their values
+	ReuseValues bool
+	// StringPolicy decides how general objects and interface{} fields decode TypeString, string if not set.
+	// Either way, the value is encoded back to same bytes
+	StringPolicy spi.StringPolicy
 }
 
 type API interface {
@@ -28,14 +54,42 @@ type API interface {
 	UnmarshalMessage(buf []byte) (general.Message, error)
 	// Marshal to []byte
 	Marshal(obj interface{}) ([]byte, error)
+	// MarshalTo appends the encoded obj to dst and returns the extended buffer
+	MarshalTo(dst []byte, obj interface{}) ([]byte, error)
+	// BorrowStream returns a pooled stream, it should be returned by ReturnStream of same API after use
+	BorrowStream(writer io.Writer) spi.Stream
+	// ReturnStream puts the stream back to pool, its buffer must not be used after returned
+	ReturnStream(stream spi.Stream)
+	// BorrowIterator returns a pooled iterator, it should be returned by ReturnIterator of same API after use
+	BorrowIterator(reader io.Reader, buf []byte) spi.Iterator
+	// ReturnIterator puts the iterator back to pool
+	ReturnIterator(iter spi.Iterator)
 	// ToJSON convert thrift message to JSON string
 	ToJSON(buf []byte) (string, error)
+	// Get decodes only the value at path in buf, the struct encoded
+	Get(buf []byte, path ...interface{}) (interface{}, error)
+	// GetMessage decodes only the value at path in the arguments of buf, the message encoded
+	GetMessage(buf []byte, path ...interface{}) (interface{}, error)
+	// Set patches the value at path in buf, the bytes not on the path are copied as they are
+	Set(buf []byte, value interface{}, path ...interface{}) ([]byte, error)
+	// SetMessage patches the value at path in the arguments of buf, the message encoded
+	SetMessage(buf []byte, value interface{}, path ...interface{}) ([]byte, error)
+	// Delete patches buf to remove the value at path
+	Delete(buf []byte, path ...interface{}) ([]byte, error)
+	// DeleteMessage patches the arguments of buf to remove the value at path
+	DeleteMessage(buf []byte, path ...interface{}) ([]byte, error)
+	// DiffRaw compares the structs decoding only the fields of different bytes
+	DiffRaw(old raw.Struct, new raw.Struct) ([]general.Difference, error)
 	// MarshalMessage to []byte
 	MarshalMessage(msg general.Message) ([]byte, error)
 	// NewDecoder to unmarshal from []byte or io.Reader
 	NewDecoder(reader io.Reader, buf []byte) *Decoder
 	// NewEncoder to marshal to io.Writer
 	NewEncoder(writer io.Writer) *Encoder
+	// NewAutoDecoder detects the protocol and framing from the leading bytes of reader, see Decoder.Format
+	NewAutoDecoder(reader io.Reader) *Decoder
+	// NewFormatEncoder to marshal to io.Writer in format, framed message is written when its arguments are encoded
+	NewFormatEncoder(writer io.Writer, format Format) *Encoder
 	// WillDecodeFromBuffer should only be used in generic.Declare
 	WillDecodeFromBuffer(sample ...interface{})
 	// WillDecodeFromReader should only be used in generic.Declare
@@ -68,10 +122,49 @@ func ToJSON(buf []byte) (string, error) {
 	return DefaultConfig.ToJSON(buf)
 }
 
+// Get decodes the value at path in buf encoded in binary protocol, like general.Struct Get
+func Get(buf []byte, path ...interface{}) (interface{}, error) {
+	return DefaultConfig.Get(buf, path...)
+}
+
+// GetMessage decodes the value at path in the arguments of the message encoded in binary protocol
+func GetMessage(buf []byte, path ...interface{}) (interface{}, error) {
+	return DefaultConfig.GetMessage(buf, path...)
+}
+
+// Set patches the value at path in buf encoded in binary protocol, like general.Struct Set
+func Set(buf []byte, value interface{}, path ...interface{}) ([]byte, error) {
+	return DefaultConfig.Set(buf, value, path...)
+}
+
+// SetMessage patches the value at path in the arguments of the message encoded in binary protocol
+func SetMessage(buf []byte, value interface{}, path ...interface{}) ([]byte, error) {
+	return DefaultConfig.SetMessage(buf, value, path...)
+}
+
+// Delete patches buf encoded in binary protocol to remove the value at path, like general.Struct Delete
+func Delete(buf []byte, path ...interface{}) ([]byte, error) {
+	return DefaultConfig.Delete(buf, path...)
+}
+
+// DeleteMessage patches the arguments of the message encoded in binary protocol to remove the value at path
+func DeleteMessage(buf []byte, path ...interface{}) ([]byte, error) {
+	return DefaultConfig.DeleteMessage(buf, path...)
+}
+
+// DiffRaw compares the structs encoded in binary protocol, see general.Diff for the differences returned
+func DiffRaw(old raw.Struct, new raw.Struct) ([]general.Difference, error) {
+	return DefaultConfig.DiffRaw(old, new)
+}
+
 func Marshal(obj interface{}) ([]byte, error) {
 	return DefaultConfig.Marshal(obj)
 }
 
+func MarshalTo(dst []byte, obj interface{}) ([]byte, error) {
+	return DefaultConfig.MarshalTo(dst, obj)
+}
+
 // MarshalMessage is just a shortcut to demonstrate message decoded by UnmarshalMessage can be encoded back
 func MarshalMessage(msg general.Message) ([]byte, error) {
 	return DefaultConfig.MarshalMessage(msg)
@@ -84,3 +177,11 @@ func NewDecoder(reader io.Reader, buf []byte) *Decoder {
 func NewEncoder(writer io.Writer) *Encoder {
 	return DefaultConfig.NewEncoder(writer)
 }
+
+func NewAutoDecoder(reader io.Reader) *Decoder {
+	return DefaultConfig.NewAutoDecoder(reader)
+}
+
+func NewFormatEncoder(writer io.Writer, format Format) *Encoder {
+	return DefaultConfig.NewFormatEncoder(writer, format)
+}
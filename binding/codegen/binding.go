@@ -1,13 +1,19 @@
 package codegen
 
 import (
+	"fmt"
 	"reflect"
 	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/spi"
 	"strings"
 	"strconv"
 )
 
 var byteArrayType = reflect.TypeOf(([]byte)(nil))
+var byteType = reflect.TypeOf(byte(0))
+var rawStructType = reflect.TypeOf(raw.Struct(nil))
+var presenceType = reflect.TypeOf(protocol.Presence{})
 
 var simpleValueMap = map[reflect.Kind]string{
 	reflect.Int:     "Int",
@@ -53,18 +59,24 @@ func isEnumType(valType reflect.Type) bool {
 
 func calcBindings(valType reflect.Type) interface{} {
 	bindings := []interface{}{}
+	presence := presenceField(valType)
 	for i := 0; i < valType.NumField(); i++ {
 		field := valType.Field(i)
 		fieldId := protocol.FieldId(0)
+		var options []string
 		thriftTag := field.Tag.Get("thrift")
 		if thriftTag != "" {
 			parts := strings.Split(thriftTag, ",")
+			if len(parts) >= 2 && parts[1] == "unknown" {
+				continue
+			}
 			if len(parts) >= 2 {
 				n, err := strconv.Atoi(parts[1])
 				if err != nil {
 					panic("thrift tag must be integer")
 				}
 				fieldId = protocol.FieldId(n)
+				options = parts[2:]
 			}
 		}
 		if fieldId == 0 {
@@ -74,7 +86,83 @@ func calcBindings(valType reflect.Type) interface{} {
 			"fieldId":   fieldId,
 			"fieldName": field.Name,
 			"fieldType": reflect.PtrTo(field.Type),
+			"options":   options,
+			"presence":  presence,
 		})
 	}
 	return bindings
-}
\ No newline at end of file
+}
+
+// unknownField is the name of raw.Struct field tagged `thrift:",unknown"`, empty if not found
+func unknownField(valType reflect.Type) string {
+	for i := 0; i < valType.NumField(); i++ {
+		field := valType.Field(i)
+		parts := strings.Split(field.Tag.Get("thrift"), ",")
+		if len(parts) >= 2 && parts[1] == "unknown" && field.Type == rawStructType {
+			return field.Name
+		}
+	}
+	return ""
+}
+
+// presenceField is the name of protocol.Presence field, empty if the struct does not track field presence
+func presenceField(valType reflect.Type) string {
+	for i := 0; i < valType.NumField(); i++ {
+		field := valType.Field(i)
+		if field.Type == presenceType {
+			return field.Name
+		}
+	}
+	return ""
+}
+
+// omitCondition is the go expression telling if the struct field should be skipped, empty if never skipped
+func omitCondition(extension *Extension, binding map[string]interface{}) string {
+	fieldType := binding["fieldType"].(reflect.Type).Elem()
+	fieldName := "src." + binding["fieldName"].(string)
+	options := binding["options"].([]string)
+	presence := binding["presence"].(string)
+	switch fieldType.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if presence != "" {
+			return fmt.Sprintf("!src.%s.IsSet(%d) || %s == nil", presence, binding["fieldId"], fieldName)
+		}
+		return fieldName + " == nil"
+	}
+	if presence != "" {
+		// with presence tracked, exactly the fields set are written
+		return fmt.Sprintf("!src.%s.IsSet(%d)", presence, binding["fieldId"])
+	}
+	omitEmpty := extension.OmitPolicy == spi.OmitZero || spi.HasOption(options, "omitempty")
+	isExtType := extension.EncoderOf(fieldType) != nil || extension.FieldEncoderOf(fieldType, options) != nil
+	switch fieldType.Kind() {
+	case reflect.Slice, reflect.Map:
+		if omitEmpty {
+			return "len(" + fieldName + ") == 0"
+		}
+		if extension.OmitPolicy != spi.OmitNever && !isExtType {
+			return fieldName + " == nil"
+		}
+		return ""
+	}
+	if !omitEmpty {
+		return ""
+	}
+	return emptyCondition(fieldType, fieldName)
+}
+
+// emptyCondition is the go expression telling if the struct field is empty, empty if it can not tell
+func emptyCondition(fieldType reflect.Type, fieldName string) string {
+	switch fieldType.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return "len(" + fieldName + ") == 0"
+	case reflect.Bool:
+		return "!" + fieldName
+	case reflect.Struct:
+		return ""
+	}
+	if _, isSimpleValue := simpleValueMap[fieldType.Kind()]; isSimpleValue {
+		return fieldName + " == 0"
+	}
+	return ""
+}
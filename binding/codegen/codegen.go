@@ -7,10 +7,58 @@ import (
 
 type Extension struct {
 	spi.Extension
-	ExtTypes []reflect.Type
+	ExtTypes   []reflect.Type
+	ExtFields  []ExtField
+	OmitPolicy spi.OmitPolicy
+	// ReuseValues makes decoders reuse the pointers, slices and maps already in the value
+	ReuseValues bool
+}
+
+// ExtField is a struct field encoded by spi.FieldExtension according to its tag options
+type ExtField struct {
+	Name    string
+	Type    reflect.Type
+	Options []string
 }
 
 func (ext *Extension) MangledName() string {
 	// TODO: hash extension to represent different config
-	return "default"
-}
\ No newline at end of file
+	name := "default"
+	switch ext.OmitPolicy {
+	case spi.OmitZero:
+		name = "omitZero"
+	case spi.OmitNever:
+		name = "omitNever"
+	}
+	if ext.ReuseValues {
+		name += "Reuse"
+	}
+	return name
+}
+
+func (ext *Extension) FieldDecoderOf(valType reflect.Type, options []string) spi.ValDecoder {
+	fieldExtension, isFieldExtension := ext.Extension.(spi.FieldExtension)
+	if !isFieldExtension || len(options) == 0 {
+		return nil
+	}
+	return fieldExtension.FieldDecoderOf(valType, options)
+}
+
+func (ext *Extension) FieldEncoderOf(valType reflect.Type, options []string) spi.ValEncoder {
+	fieldExtension, isFieldExtension := ext.Extension.(spi.FieldExtension)
+	if !isFieldExtension || len(options) == 0 {
+		return nil
+	}
+	return fieldExtension.FieldEncoderOf(valType, options)
+}
+
+func (ext *Extension) addExtField(valType reflect.Type, options []string) string {
+	name := spi.FieldCodecName(valType, options)
+	for _, extField := range ext.ExtFields {
+		if extField.Name == name {
+			return name
+		}
+	}
+	ext.ExtFields = append(ext.ExtFields, ExtField{Name: name, Type: valType, Options: options})
+	return name
+}
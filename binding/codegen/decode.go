@@ -19,5 +19,10 @@ iter := src.({{.ST|name}})
 		iter.PrepareDecoder(reflect.TypeOf((*{{$extType|name}})(nil)).Elem())
 	}
 {{ end }}
+{{ range $extField := .EXT.ExtFields }}
+	if iter.GetDecoder("{{$extField.Name}}") == nil {
+		iter.PrepareFieldDecoder(reflect.TypeOf((*{{$extField.Type|name}})(nil)).Elem(){{ range $option := $extField.Options }}, "{{$option}}"{{ end }})
+	}
+{{ end }}
 {{$decode}}(dst.({{.DT|name}}), iter)
 `)
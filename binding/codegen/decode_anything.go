@@ -23,6 +23,11 @@ func dispatchDecode(extension *Extension, dstType reflect.Type) string {
 	switch dstType.Kind() {
 	case reflect.Slice:
 		return "DecodeSlice"
+	case reflect.Array:
+		if dstType.Elem() == byteType {
+			return "DecodeByteArray"
+		}
+		return "DecodeArray"
 	case reflect.Map:
 		return "DecodeMap"
 	case reflect.Struct:
@@ -0,0 +1,50 @@
+package codegen
+
+import (
+	"github.com/v2pro/wombat/generic"
+	"reflect"
+)
+
+func init() {
+	decodeAnything.ImportFunc(decodeArray)
+	decodeAnything.ImportFunc(decodeByteArray)
+}
+
+var decodeArray = generic.DefineFunc(
+	"DecodeArray(dst DT, src ST)").
+	Param("EXT", "user provided extension").
+	Param("DT", "the dst type to copy into").
+	Param("ST", "the src type to copy from").
+	ImportPackage("fmt").
+	ImportFunc(decodeAnything).
+	Generators(
+		"ptrArrayElem", func(typ reflect.Type) reflect.Type {
+			return reflect.PtrTo(typ.Elem().Elem())
+		}).
+	Source(`
+{{ $decodeElem := expand "DecodeAnything" "EXT" .EXT "DT" (.DT|ptrArrayElem) "ST" .ST }}
+_, length := src.ReadListHeader()
+if length != len(*dst) {
+	src.ReportError("decode {{.DT|elem|name}}", fmt.Sprintf("expected %d elements but got %d", len(*dst), length))
+	return
+}
+for i := 0; i < length; i++ {
+	{{$decodeElem}}(&(*dst)[i], src)
+}`)
+
+var decodeByteArray = generic.DefineFunc(
+	"DecodeByteArray(dst DT, src ST)").
+	Param("EXT", "user provided extension").
+	Param("DT", "the dst type to copy into").
+	Param("ST", "the src type to copy from").
+	ImportPackage("fmt").
+	Source(`
+buf := src.ReadBinary()
+if src.Error() != nil {
+	return
+}
+if len(buf) != len(*dst) {
+	src.ReportError("decode {{.DT|elem|name}}", fmt.Sprintf("expected %d bytes but got %d", len(*dst), len(buf)))
+	return
+}
+copy((*dst)[:], buf)`)
@@ -26,12 +26,28 @@ var decodeMap = generic.DefineFunc(
 {{ $decodeElem := expand "DecodeAnything" "EXT" .EXT "DT" (.DT|ptrMapElem) "ST" .ST }}
 if *dst == nil {
 	*dst = {{.DT|elem|name}}{}
-}
+}{{ if .EXT.ReuseValues }} else {
+	for key := range *dst {
+		delete(*dst, key)
+	}
+}{{ end }}
 _, _, length := src.ReadMapHeader()
+{{ if .EXT.ReuseValues }}
+var newKey, zeroKey {{.DT|elem|key|name}}
+var newElem, zeroElem {{.DT|elem|elem|name}}
+for i := 0; i < length; i++ {
+	newKey = zeroKey
+	{{$decodeKey}}(&newKey, src)
+	newElem = zeroElem
+	{{$decodeElem}}(&newElem, src)
+	(*dst)[newKey] = newElem
+}
+{{ else }}
 for i := 0; i < length; i++ {
 	newKey := new({{.DT|elem|key|name}})
 	{{$decodeKey}}(newKey, src)
 	newElem := new({{.DT|elem|elem|name}})
 	{{$decodeElem}}(newElem, src)
 	(*dst)[*newKey] = *newElem
-}`)
\ No newline at end of file
+}
+{{ end }}`)
\ No newline at end of file
@@ -16,6 +16,12 @@ var decodePointer = generic.DefineFunc(
 	ImportFunc(decodeAnything).
 	Source(`
 {{ $decode := expand "DecodeAnything" "EXT" .EXT "DT" (.DT|elem) "ST" .ST }}
+{{ if .EXT.ReuseValues }}
+if *dst != nil {
+	{{$decode}}(*dst, src)
+	return
+}
+{{ end }}
 defDst := new({{ .DT|elem|elem|name }})
 {{$decode}}(defDst, src)
 *dst = defDst
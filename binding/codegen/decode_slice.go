@@ -22,8 +22,19 @@ var decodeSlice = generic.DefineFunc(
 	Source(`
 {{ $decodeElem := expand "DecodeAnything" "EXT" .EXT "DT" (.DT|ptrSliceElem) "ST" .ST }}
 _, length := src.ReadListHeader()
+{{ if .EXT.ReuseValues }}
+if cap(*dst) < length {
+	*dst = make({{.DT|elem|name}}, length)
+} else {
+	*dst = (*dst)[:length]
+}
+for i := 0; i < length; i++ {
+	{{$decodeElem}}(&(*dst)[i], src)
+}
+{{ else }}
 for i := 0; i < length; i++ {
 	elem := new({{.DT|elem|elem|name}})
 	{{$decodeElem}}(elem, src)
 	*dst = append(*dst, *elem)
-}`)
\ No newline at end of file
+}
+{{ end }}`)
\ No newline at end of file
@@ -2,6 +2,7 @@ package codegen
 
 import (
 	"github.com/v2pro/wombat/generic"
+	"reflect"
 )
 
 func init() {
@@ -16,17 +17,42 @@ var decodeStruct = generic.DefineFunc(
 	ImportFunc(decodeAnything).
 	Generators(
 	"calcBindings", calcBindings,
+	"unknownField", unknownField,
+	"presenceField", presenceField,
 	"assignDecode", func(binding map[string]interface{}, decodeFuncName string) string {
 		binding["decode"] = decodeFuncName
 		return ""
+	},
+	"assignFieldDecoder", func(extension *Extension, binding map[string]interface{}) string {
+		fieldType := binding["fieldType"].(reflect.Type)
+		options := binding["options"].([]string)
+		if extension.FieldDecoderOf(fieldType, options) != nil {
+			binding["extName"] = extension.addExtField(fieldType, options)
+		} else if fieldType.Elem().Kind() == reflect.Ptr && extension.FieldDecoderOf(fieldType.Elem(), options) != nil {
+			// pointer field is allocated then decoded by the extension, same as reflection
+			binding["extName"] = extension.addExtField(fieldType.Elem(), options)
+			binding["extElem"] = fieldType.Elem().Elem()
+		}
+		return ""
 	}).
 	Source(`
 {{ $bindings := calcBindings (.DT|elem) }}
+{{ $unknown := unknownField (.DT|elem) }}
+{{ $presence := presenceField (.DT|elem) }}
 {{ range $_, $binding := $bindings}}
-	{{ $decode := expand "DecodeAnything" "EXT" $.EXT "DT" $binding.fieldType "ST" $.ST }}
-	{{ assignDecode $binding $decode }}
+	{{ assignFieldDecoder $.EXT $binding }}
+	{{ if not $binding.extName }}
+		{{ $decode := expand "DecodeAnything" "EXT" $.EXT "DT" $binding.fieldType "ST" $.ST }}
+		{{ assignDecode $binding $decode }}
+	{{ end }}
 {{ end }}
 src.ReadStructHeader()
+{{ if $presence }}
+	dst.{{ $presence }}.Reset()
+{{ end }}
+{{ if $unknown }}
+	dst.{{ $unknown }} = nil
+{{ end }}
 for {
 	fieldType, fieldId := src.ReadStructField()
 	if fieldType == 0 {
@@ -35,9 +61,29 @@ for {
 	switch fieldId {
 		{{ range $_, $binding := $bindings }}
 			case {{ $binding.fieldId }}:
-				{{$binding.decode}}(&dst.{{$binding.fieldName}}, src)
+				{{ if $binding.extElem }}
+					{{ if $.EXT.ReuseValues }}
+					if dst.{{$binding.fieldName}} == nil {
+						dst.{{$binding.fieldName}} = new({{ $binding.extElem|name }})
+					}
+					{{ else }}
+					dst.{{$binding.fieldName}} = new({{ $binding.extElem|name }})
+					{{ end }}
+					src.GetDecoder("{{ $binding.extName }}").Decode(dst.{{$binding.fieldName}}, src)
+				{{ else if $binding.extName }}
+					src.GetDecoder("{{ $binding.extName }}").Decode(&dst.{{$binding.fieldName}}, src)
+				{{ else }}
+					{{$binding.decode}}(&dst.{{$binding.fieldName}}, src)
+				{{ end }}
+				{{ if $presence }}
+					dst.{{ $presence }}.Set({{ $binding.fieldId }})
+				{{ end }}
 		{{ end }}
 		default:
-			src.Discard(fieldType)
+			{{ if $unknown }}
+				dst.{{ $unknown }}.ReadField(src, fieldType, fieldId)
+			{{ else }}
+				src.Discard(fieldType)
+			{{ end }}
 	}
 }`)
\ No newline at end of file
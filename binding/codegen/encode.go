@@ -17,5 +17,10 @@ stream := dst.({{.DT|name}})
 		stream.PrepareEncoder(reflect.TypeOf((*{{$extType|name}})(nil)).Elem())
 	}
 {{ end }}
+{{ range $extField := .EXT.ExtFields }}
+	if stream.GetEncoder("{{$extField.Name}}") == nil {
+		stream.PrepareFieldEncoder(reflect.TypeOf((*{{$extField.Type|name}})(nil)).Elem(){{ range $option := $extField.Options }}, "{{$option}}"{{ end }})
+	}
+{{ end }}
 {{$decode}}(stream, src.({{.ST|name}}))
 `)
@@ -21,6 +21,11 @@ func dispatchEncode(extension *Extension, srcType reflect.Type) (string, protoco
 	switch srcType.Kind() {
 	case reflect.Slice:
 		return "EncodeSlice", protocol.TypeList
+	case reflect.Array:
+		if srcType.Elem() == byteType {
+			return "EncodeByteArray", protocol.TypeString
+		}
+		return "EncodeArray", protocol.TypeList
 	case reflect.Map:
 		return "EncodeMap", protocol.TypeMap
 	case reflect.Struct:
@@ -0,0 +1,35 @@
+package codegen
+
+import (
+	"github.com/v2pro/wombat/generic"
+)
+
+func init() {
+	encodeAnything.ImportFunc(encodeArray)
+	encodeAnything.ImportFunc(encodeByteArray)
+}
+
+var encodeArray = generic.DefineFunc(
+	"EncodeArray(dst DT, src ST)").
+	Param("EXT", "user provided extension").
+	Param("DT", "the dst type to copy into").
+	Param("ST", "the src type to copy from").
+	ImportFunc(encodeAnything).
+	Generators(
+		"thriftType", dispatchThriftType).
+	Source(`
+{{ $encodeElem := expand "EncodeAnything" "EXT" .EXT "DT" .DT "ST" (.ST|elem) }}
+dst.WriteListHeader({{.ST|elem|thriftType .EXT }}, len(src))
+for _, elem := range src {
+	{{$encodeElem}}(dst, elem)
+}
+`)
+
+var encodeByteArray = generic.DefineFunc(
+	"EncodeByteArray(dst DT, src ST)").
+	Param("EXT", "user provided extension").
+	Param("DT", "the dst type to copy into").
+	Param("ST", "the src type to copy from").
+	Source(`
+dst.WriteBinary(src[:])
+	`)
@@ -2,6 +2,7 @@ package codegen
 
 import (
 	"github.com/v2pro/wombat/generic"
+	"reflect"
 )
 
 func init() {
@@ -16,18 +17,58 @@ var encodeStruct = generic.DefineFunc(
 	ImportFunc(encodeAnything).
 	Generators(
 	"calcBindings", calcBindings,
+	"unknownField", unknownField,
 	"assignEncode", func(binding map[string]interface{}, encodeFuncName string) string {
 		binding["encode"] = encodeFuncName
 		return ""
 	},
+	"assignFieldEncoder", func(extension *Extension, binding map[string]interface{}) string {
+		fieldType := binding["fieldType"].(reflect.Type).Elem()
+		options := binding["options"].([]string)
+		extEncoder := extension.FieldEncoderOf(fieldType, options)
+		if extEncoder == nil && fieldType.Kind() == reflect.Ptr {
+			// nil pointer field is omitted, otherwise the pointed value is encoded by the extension
+			extEncoder = extension.FieldEncoderOf(fieldType.Elem(), options)
+			if extEncoder != nil {
+				fieldType = fieldType.Elem()
+				binding["extDeref"] = true
+			}
+		}
+		if extEncoder != nil {
+			binding["extName"] = extension.addExtField(fieldType, options)
+			binding["extThriftType"] = int(extEncoder.ThriftType())
+		}
+		return ""
+	},
+	"omitCondition", omitCondition,
 	"thriftType", dispatchThriftType).
 	Source(`
 {{ $bindings := calcBindings .ST }}
 dst.WriteStructHeader()
+if dst.Error() != nil {
+	return
+}
 {{ range $_, $binding := $bindings}}
-	{{ $encode := expand "EncodeAnything" "EXT" $.EXT "DT" $.DT "ST" $binding.fieldType }}
-	dst.WriteStructField({{$binding.fieldType|thriftType .EXT}}, {{$binding.fieldId}})
-	{{$encode}}(dst, &src.{{$binding.fieldName}})
+	{{ assignFieldEncoder $.EXT $binding }}
+	{{ $omit := omitCondition $.EXT $binding }}
+	{{ if $omit }}
+	if !({{ $omit }}) {
+	{{ end }}
+	{{ if $binding.extName }}
+		dst.WriteStructField({{$binding.extThriftType}}, {{$binding.fieldId}})
+		dst.GetEncoder("{{ $binding.extName }}").Encode({{ if $binding.extDeref }}*{{ end }}src.{{$binding.fieldName}}, dst)
+	{{ else }}
+		{{ $encode := expand "EncodeAnything" "EXT" $.EXT "DT" $.DT "ST" $binding.fieldType }}
+		dst.WriteStructField({{$binding.fieldType|thriftType $.EXT}}, {{$binding.fieldId}})
+		{{$encode}}(dst, &src.{{$binding.fieldName}})
+	{{ end }}
+	{{ if $omit }}
+	}
+	{{ end }}
+{{ end }}
+{{ $unknown := unknownField .ST }}
+{{ if $unknown }}
+	src.{{ $unknown }}.WriteFields(dst{{ range $_, $binding := $bindings }}, {{ $binding.fieldId }}{{ end }})
 {{ end }}
 dst.WriteStructFieldStop()
 `)
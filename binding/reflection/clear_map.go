@@ -0,0 +1,10 @@
+//go:build go1.21
+// +build go1.21
+
+package reflection
+
+import "reflect"
+
+func clearMap(mapVal reflect.Value) {
+	mapVal.Clear()
+}
@@ -0,0 +1,14 @@
+//go:build !go1.21
+// +build !go1.21
+
+package reflection
+
+import "reflect"
+
+// clearMap copies the keys out of map one by one before go1.21
+func clearMap(mapVal reflect.Value) {
+	zero := reflect.Value{}
+	for iter := mapVal.MapRange(); iter.Next(); {
+		mapVal.SetMapIndex(iter.Key(), zero)
+	}
+}
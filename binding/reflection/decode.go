@@ -11,16 +11,20 @@ import (
 )
 
 var byteSliceType = reflect.TypeOf(([]byte)(nil))
+var byteType = reflect.TypeOf(byte(0))
 
 func DecoderOf(extension spi.Extension, valType reflect.Type) spi.ValDecoder {
 	if valType.Kind() != reflect.Ptr {
 		return &valDecoderAdapter{&unknownDecoder{
 			prefix: "unmarshal into non-pointer type", valType: valType}}
 	}
-	return &valDecoderAdapter{decoderOf(extension, "", valType.Elem())}
+	return &valDecoderAdapter{decoderOf(extensionOf(extension).forBuilding(), "", valType.Elem())}
 }
 
-func decoderOf(extension spi.Extension, prefix string, valType reflect.Type) internalDecoder {
+func decoderOf(extension *Extension, prefix string, valType reflect.Type) internalDecoder {
+	if decoder, found := extension.decoders[valType]; found {
+		return decoder
+	}
 	extDecoder := extension.DecoderOf(reflect.PtrTo(valType))
 	if extDecoder != nil {
 		valObj := reflect.New(valType).Interface()
@@ -61,51 +65,89 @@ func decoderOf(extension spi.Extension, prefix string, valType reflect.Type) int
 	case reflect.String:
 		return &stringDecoder{}
 	case reflect.Ptr:
-		return &pointerDecoder{
-			valType:    valType.Elem(),
-			valDecoder: decoderOf(extension, prefix+" [ptrElem]", valType.Elem()),
-		}
+		decoder := &pointerDecoder{valType: valType.Elem(), reuse: extension.ReuseValues}
+		extension.decoders[valType] = decoder
+		decoder.valDecoder = decoderOf(extension, prefix+" [ptrElem]", valType.Elem())
+		return decoder
 	case reflect.Slice:
-		return &sliceDecoder{
-			elemType:    valType.Elem(),
-			sliceType:   valType,
-			elemDecoder: decoderOf(extension, prefix+" [sliceElem]", valType.Elem()),
+		decoder := &sliceDecoder{elemType: valType.Elem(), sliceType: valType}
+		extension.decoders[valType] = decoder
+		decoder.elemDecoder = decoderOf(extension, prefix+" [sliceElem]", valType.Elem())
+		return decoder
+	case reflect.Array:
+		if valType.Elem() == byteType {
+			return &byteArrayDecoder{arrayType: valType}
 		}
+		decoder := &arrayDecoder{arrayType: valType, elemType: valType.Elem()}
+		extension.decoders[valType] = decoder
+		decoder.elemDecoder = decoderOf(extension, prefix+" [arrayElem]", valType.Elem())
+		return decoder
 	case reflect.Map:
 		sampleObj := reflect.New(valType).Interface()
-		return &mapDecoder{
+		decoder := &mapDecoder{
 			keyType:      valType.Key(),
-			keyDecoder:   decoderOf(extension, prefix+" [mapKey]", valType.Key()),
 			elemType:     valType.Elem(),
-			elemDecoder:  decoderOf(extension, prefix+" [mapElem]", valType.Elem()),
 			mapType:      valType,
 			mapInterface: *(*emptyInterface)(unsafe.Pointer(&sampleObj)),
+			reuse:        extension.ReuseValues,
 		}
+		decoder.entries.New = decoder.newEntry
+		extension.decoders[valType] = decoder
+		decoder.keyDecoder = decoderOf(extension, prefix+" [mapKey]", valType.Key())
+		decoder.elemDecoder = decoderOf(extension, prefix+" [mapElem]", valType.Elem())
+		return decoder
 	case reflect.Struct:
-		decoderFields := make([]structDecoderField, 0, valType.NumField())
+		decoder := &structDecoder{}
+		extension.decoders[valType] = decoder
+		boundFields := boundFieldsOf(valType)
+		decoderFields := make([]structDecoderField, 0, len(boundFields))
 		decoderFieldMap := map[protocol.FieldId]structDecoderField{}
-		for i := 0; i < valType.NumField(); i++ {
-			refField := valType.Field(i)
-			fieldId := parseFieldId(refField)
-			if fieldId == -1 {
-				continue
-			}
+		for _, boundField := range boundFields {
 			decoderField := structDecoderField{
-				offset:  refField.Offset,
-				fieldId: fieldId,
-				decoder: decoderOf(extension, prefix+" "+refField.Name, refField.Type),
+				offset:   boundField.offset,
+				embedded: boundField.embedded,
+				fieldId:  boundField.fieldId,
+				decoder: fieldDecoderOf(extension, prefix+" "+boundField.name,
+					boundField.valType, boundField.options),
 			}
 			decoderFields = append(decoderFields, decoderField)
-			decoderFieldMap[fieldId] = decoderField
+			decoderFieldMap[boundField.fieldId] = decoderField
 		}
-		return &structDecoder{
-			fields:   decoderFields,
-			fieldMap: decoderFieldMap,
+		decoder.fields = decoderFields
+		decoder.fieldMap = decoderFieldMap
+		decoder.unknownOffset, decoder.hasUnknown = unknownFieldOf(valType)
+		decoder.presenceOffset, decoder.hasPresence = presenceFieldOf(valType)
+		return decoder
+	case reflect.Interface:
+		if valType.NumMethod() == 0 {
+			return &interfaceDecoder{stringPolicy: extension.StringPolicy}
 		}
 	}
 	return &unknownDecoder{prefix, valType}
 }
 
+// fieldDecoderOf gives extensions implementing spi.FieldExtension a chance to
+// decode the field according to its tag options
+func fieldDecoderOf(extension *Extension, prefix string, valType reflect.Type, options []string) internalDecoder {
+	if len(options) == 0 {
+		return decoderOf(extension, prefix, valType)
+	}
+	extDecoder := extension.FieldDecoderOf(reflect.PtrTo(valType), options)
+	if extDecoder != nil {
+		valObj := reflect.New(valType).Interface()
+		valEmptyInterface := *(*emptyInterface)(unsafe.Pointer(&valObj))
+		return &internalDecoderAdapter{valEmptyInterface: valEmptyInterface, decoder: extDecoder}
+	}
+	if valType.Kind() == reflect.Ptr {
+		return &pointerDecoder{
+			valType:    valType.Elem(),
+			reuse:      extension.ReuseValues,
+			valDecoder: fieldDecoderOf(extension, prefix+" [ptrElem]", valType.Elem(), options),
+		}
+	}
+	return decoderOf(extension, prefix, valType)
+}
+
 func isEnumType(valType reflect.Type) bool {
 	if valType.Kind() != reflect.Int64 {
 		return false
@@ -114,23 +156,24 @@ func isEnumType(valType reflect.Type) bool {
 	return hasStringMethod
 }
 
-func parseFieldId(refField reflect.StructField) protocol.FieldId {
+// parseFieldTag parses `thrift:"name,id,options..."`, returning -1 as field id if not bound
+func parseFieldTag(refField reflect.StructField) (protocol.FieldId, []string) {
 	if !unicode.IsUpper(rune(refField.Name[0])) {
-		return -1
+		return -1, nil
 	}
 	thriftTag := refField.Tag.Get("thrift")
 	if thriftTag == "" {
-		return -1
+		return -1, nil
 	}
 	parts := strings.Split(thriftTag, ",")
 	if len(parts) < 2 {
-		return -1
+		return -1, nil
 	}
 	fieldId, err := strconv.Atoi(parts[1])
 	if err != nil {
-		return -1
+		return -1, nil
 	}
-	return protocol.FieldId(fieldId)
+	return protocol.FieldId(fieldId), parts[2:]
 }
 
 type unknownDecoder struct {
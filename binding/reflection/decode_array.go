@@ -0,0 +1,47 @@
+package reflection
+
+import (
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"reflect"
+	"unsafe"
+)
+
+type arrayDecoder struct {
+	arrayType   reflect.Type
+	elemType    reflect.Type
+	elemDecoder internalDecoder
+}
+
+func (decoder *arrayDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
+	elemType, length := iter.ReadListHeader()
+	if length != decoder.arrayType.Len() {
+		iter.ReportError("decode "+decoder.arrayType.String(),
+			fmt.Sprintf("expected %d elements but got %d", decoder.arrayType.Len(), length))
+		return
+	}
+	offset := uintptr(0)
+	for i := 0; i < length; i++ {
+		decodeTyped(decoder.elemDecoder, unsafe.Pointer(uintptr(ptr)+offset), iter, elemType)
+		offset += decoder.elemType.Size()
+	}
+}
+
+// byteArrayDecoder decodes binary into [N]byte
+type byteArrayDecoder struct {
+	arrayType reflect.Type
+}
+
+func (decoder *byteArrayDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
+	buf := iter.ReadBinary()
+	if iter.Error() != nil {
+		return
+	}
+	length := decoder.arrayType.Len()
+	if len(buf) != length {
+		iter.ReportError("decode "+decoder.arrayType.String(),
+			fmt.Sprintf("expected %d bytes but got %d", length, len(buf)))
+		return
+	}
+	copy(*(*[]byte)(unsafe.Pointer(&sliceHeader{ptr, length, length})), buf)
+}
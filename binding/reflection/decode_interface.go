@@ -0,0 +1,34 @@
+package reflection
+
+import (
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"unsafe"
+)
+
+// typedDecoder decodes value whose thrift type can not be told from the go type
+type typedDecoder interface {
+	decodeTyped(ptr unsafe.Pointer, iter spi.Iterator, ttype protocol.TType)
+}
+
+func decodeTyped(decoder internalDecoder, ptr unsafe.Pointer, iter spi.Iterator, ttype protocol.TType) {
+	if typed, isTyped := decoder.(typedDecoder); isTyped {
+		typed.decodeTyped(ptr, iter, ttype)
+		return
+	}
+	decoder.decode(ptr, iter)
+}
+
+// interfaceDecoder decodes interface{} into general objects, like general.Struct
+type interfaceDecoder struct {
+	stringPolicy spi.StringPolicy
+}
+
+func (decoder *interfaceDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
+	iter.ReportError("decode interface{}", "thrift type is unknown outside of struct, list or map")
+}
+
+func (decoder *interfaceDecoder) decodeTyped(ptr unsafe.Pointer, iter spi.Iterator, ttype protocol.TType) {
+	*(*interface{})(ptr) = general.ReadWithPolicy(iter, ttype, decoder.stringPolicy)
+}
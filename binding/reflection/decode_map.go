@@ -3,6 +3,7 @@ package reflection
 import (
 	"github.com/batchcorp/thrift-iterator/spi"
 	"reflect"
+	"sync"
 	"unsafe"
 )
 
@@ -13,6 +14,19 @@ type mapDecoder struct {
 	keyDecoder   internalDecoder
 	elemType     reflect.Type
 	elemDecoder  internalDecoder
+	// reuse clears the existing map instead of merging into it
+	reuse bool
+	// entries holds *mapEntry to decode the key and elem into before copying them into map
+	entries sync.Pool
+}
+
+type mapEntry struct {
+	key  reflect.Value
+	elem reflect.Value
+}
+
+func (decoder *mapDecoder) newEntry() interface{} {
+	return &mapEntry{key: reflect.New(decoder.keyType).Elem(), elem: reflect.New(decoder.elemType).Elem()}
 }
 
 func (decoder *mapDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
@@ -22,13 +36,24 @@ func (decoder *mapDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
 	mapVal := reflect.ValueOf(*realInterface).Elem()
 	if mapVal.IsNil() {
 		mapVal.Set(reflect.MakeMap(decoder.mapType))
+	} else if decoder.reuse {
+		clearMap(mapVal)
+	}
+	keyType, elemType, length := iter.ReadMapHeader()
+	if length == 0 {
+		return
 	}
-	_, _, length := iter.ReadMapHeader()
+	entry := decoder.entries.Get().(*mapEntry)
+	keyZero := reflect.Zero(decoder.keyType)
+	elemZero := reflect.Zero(decoder.elemType)
 	for i := 0; i < length; i++ {
-		keyVal := reflect.New(decoder.keyType)
-		decoder.keyDecoder.decode(unsafe.Pointer(keyVal.Pointer()), iter)
-		elemVal := reflect.New(decoder.elemType)
-		decoder.elemDecoder.decode(unsafe.Pointer(elemVal.Pointer()), iter)
-		mapVal.SetMapIndex(keyVal.Elem(), elemVal.Elem())
+		entry.key.Set(keyZero)
+		decodeTyped(decoder.keyDecoder, unsafe.Pointer(entry.key.UnsafeAddr()), iter, keyType)
+		entry.elem.Set(elemZero)
+		decodeTyped(decoder.elemDecoder, unsafe.Pointer(entry.elem.UnsafeAddr()), iter, elemType)
+		mapVal.SetMapIndex(entry.key, entry.elem)
 	}
+	entry.key.Set(keyZero)
+	entry.elem.Set(elemZero)
+	decoder.entries.Put(entry)
 }
@@ -9,9 +9,17 @@ import (
 type pointerDecoder struct {
 	valType    reflect.Type
 	valDecoder internalDecoder
+	// reuse decodes into the existing target if the pointer is not nil
+	reuse bool
 }
 
 func (decoder *pointerDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
+	if decoder.reuse {
+		if existing := *(*unsafe.Pointer)(ptr); existing != nil {
+			decoder.valDecoder.decode(existing, iter)
+			return
+		}
+	}
 	value := reflect.New(decoder.valType).Interface()
 	newPtr := (*emptyInterface)(unsafe.Pointer(&value)).word
 	decoder.valDecoder.decode(newPtr, iter)
@@ -16,7 +16,7 @@ func (decoder *sliceDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
 	slice := (*sliceHeader)(ptr)
 	slice.Len = 0
 	offset := uintptr(0)
-	_, length := iter.ReadListHeader()
+	elemType, length := iter.ReadListHeader()
 
 	if slice.Cap < length {
 		newVal := reflect.MakeSlice(decoder.sliceType, 0, length)
@@ -25,7 +25,7 @@ func (decoder *sliceDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
 	}
 
 	for i := 0; i < length; i++ {
-		decoder.elemDecoder.decode(unsafe.Pointer(uintptr(slice.Data)+offset), iter)
+		decodeTyped(decoder.elemDecoder, unsafe.Pointer(uintptr(slice.Data)+offset), iter, elemType)
 		offset += decoder.elemType.Size()
 		slice.Len += 1
 	}
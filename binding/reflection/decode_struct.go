@@ -2,27 +2,44 @@ package reflection
 
 import (
 	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
 	"github.com/batchcorp/thrift-iterator/spi"
+	"reflect"
 	"unsafe"
 )
 
 type structDecoder struct {
-	fields   []structDecoderField
-	fieldMap map[protocol.FieldId]structDecoderField
+	fields         []structDecoderField
+	fieldMap       map[protocol.FieldId]structDecoderField
+	hasUnknown     bool
+	unknownOffset  uintptr
+	hasPresence    bool
+	presenceOffset uintptr
 }
 
 type structDecoderField struct {
-	offset  uintptr
-	fieldId protocol.FieldId
-	decoder internalDecoder
+	offset   uintptr
+	embedded []embeddedPointer
+	fieldId  protocol.FieldId
+	decoder  internalDecoder
 }
 
 func (decoder *structDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
 	iter.ReadStructHeader()
+	presence := decoder.presence(ptr)
+	if presence != nil {
+		presence.Reset()
+	}
+	if decoder.hasUnknown {
+		*decoder.unknown(ptr) = nil
+	}
 	for _, field := range decoder.fields {
 		fieldType, fieldId := iter.ReadStructField()
-		if field.fieldId == fieldId {
-			field.decoder.decode(unsafe.Pointer(uintptr(ptr)+field.offset), iter)
+		if field.fieldId == fieldId && fieldType != protocol.TypeStop {
+			field.decode(ptr, iter, fieldType)
+			if presence != nil {
+				presence.Set(fieldId)
+			}
 		} else {
 			decoder.decodeByMap(ptr, iter, fieldType, fieldId)
 			return
@@ -34,16 +51,46 @@ func (decoder *structDecoder) decode(ptr unsafe.Pointer, iter spi.Iterator) {
 
 func (decoder *structDecoder) decodeByMap(ptr unsafe.Pointer, iter spi.Iterator,
 	fieldType protocol.TType, fieldId protocol.FieldId) {
+	presence := decoder.presence(ptr)
 	for {
 		if protocol.TypeStop == fieldType {
 			return
 		}
 		field, isFound := decoder.fieldMap[fieldId]
 		if isFound {
-			field.decoder.decode(unsafe.Pointer(uintptr(ptr)+field.offset), iter)
+			field.decode(ptr, iter, fieldType)
+			if presence != nil {
+				presence.Set(fieldId)
+			}
+		} else if decoder.hasUnknown {
+			decoder.unknown(ptr).ReadField(iter, fieldType, fieldId)
 		} else {
 			iter.Discard(fieldType)
 		}
 		fieldType, fieldId = iter.ReadStructField()
 	}
 }
+
+func (decoder *structDecoder) unknown(ptr unsafe.Pointer) *raw.Struct {
+	return (*raw.Struct)(unsafe.Pointer(uintptr(ptr) + decoder.unknownOffset))
+}
+
+// presence returns nil if the struct does not track field presence
+func (decoder *structDecoder) presence(ptr unsafe.Pointer) *protocol.Presence {
+	if !decoder.hasPresence {
+		return nil
+	}
+	return (*protocol.Presence)(unsafe.Pointer(uintptr(ptr) + decoder.presenceOffset))
+}
+
+func (field *structDecoderField) decode(ptr unsafe.Pointer, iter spi.Iterator, fieldType protocol.TType) {
+	for _, embedded := range field.embedded {
+		embeddedPtr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(ptr) + embedded.offset))
+		if *embeddedPtr == nil {
+			value := reflect.New(embedded.valType).Interface()
+			*embeddedPtr = (*emptyInterface)(unsafe.Pointer(&value)).word
+		}
+		ptr = *embeddedPtr
+	}
+	decodeTyped(field.decoder, unsafe.Pointer(uintptr(ptr)+field.offset), iter, fieldType)
+}
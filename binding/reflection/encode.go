@@ -8,20 +8,24 @@ import (
 )
 
 func EncoderOf(extension spi.Extension, valType reflect.Type) spi.ValEncoder {
+	reflectionExtension := extensionOf(extension).forBuilding()
 	isPtr := valType.Kind() == reflect.Ptr
 	isOnePtrArray := valType.Kind() == reflect.Array && valType.Len() == 1 &&
-		valType.Elem().Kind() == reflect.Ptr
+		(valType.Elem().Kind() == reflect.Ptr || valType.Elem().Kind() == reflect.Map)
 	isOnePtrStruct := valType.Kind() == reflect.Struct && valType.NumField() == 1 &&
 		valType.Field(0).Type.Kind() == reflect.Ptr
 	isOneMapStruct := valType.Kind() == reflect.Struct && valType.NumField() == 1 &&
 		valType.Field(0).Type.Kind() == reflect.Map
 	if isPtr || isOnePtrArray || isOnePtrStruct || isOneMapStruct {
-		return &ptrEncoderAdapter{encoderOf(extension, "", valType)}
+		return &ptrEncoderAdapter{encoderOf(reflectionExtension, "", valType)}
 	}
-	return &valEncoderAdapter{encoderOf(extension, "", valType)}
+	return &valEncoderAdapter{encoderOf(reflectionExtension, "", valType)}
 }
 
-func encoderOf(extension spi.Extension, prefix string, valType reflect.Type) internalEncoder {
+func encoderOf(extension *Extension, prefix string, valType reflect.Type) internalEncoder {
+	if encoder, found := extension.encoders[valType]; found {
+		return encoder
+	}
 	extEncoder := extension.EncoderOf(valType)
 	if extEncoder != nil {
 		valObj := reflect.New(valType).Elem().Interface()
@@ -64,47 +68,96 @@ func encoderOf(extension spi.Extension, prefix string, valType reflect.Type) int
 	case reflect.Float64:
 		return &float64Encoder{}
 	case reflect.Slice:
-		return &sliceEncoder{
-			sliceType:   valType,
-			elemType:    valType.Elem(),
-			elemEncoder: encoderOf(extension, prefix+" [sliceElem]", valType.Elem()),
+		encoder := &sliceEncoder{sliceType: valType, elemType: valType.Elem()}
+		extension.encoders[valType] = encoder
+		encoder.elemEncoder = encoderOf(extension, prefix+" [sliceElem]", valType.Elem())
+		return encoder
+	case reflect.Array:
+		if valType.Elem() == byteType {
+			return &byteArrayEncoder{arrayType: valType}
 		}
+		encoder := &arrayEncoder{arrayType: valType, elemType: valType.Elem()}
+		extension.encoders[valType] = encoder
+		encoder.elemEncoder = encoderOf(extension, prefix+" [arrayElem]", valType.Elem())
+		return encoder
 	case reflect.Map:
 		sampleObj := reflect.New(valType).Elem().Interface()
 		elemType := valType.Elem()
 		if elemType.Kind() == reflect.Ptr {
 			elemType = elemType.Elem()
 		}
-		return &mapEncoder{
-			keyEncoder:   encoderOf(extension, prefix+" [mapKey]", valType.Key()),
-			elemEncoder:  encoderOf(extension, prefix+" [mapElem]", elemType),
+		encoder := &mapEncoder{
 			mapInterface: *(*emptyInterface)(unsafe.Pointer(&sampleObj)),
+			// map encoder takes the map pointer itself, pointer elem is encoded as its target
+			keyIndirect: isPointerShaped(valType.Key()),
+			elemIndirect: valType.Elem().Kind() != reflect.Ptr && valType.Elem().Kind() != reflect.Map &&
+				isPointerShaped(valType.Elem()),
 		}
+		extension.encoders[valType] = encoder
+		encoder.keyEncoder = encoderOf(extension, prefix+" [mapKey]", valType.Key())
+		encoder.elemEncoder = encoderOf(extension, prefix+" [mapElem]", elemType)
+		return encoder
 	case reflect.Struct:
-		encoderFields := make([]structEncoderField, 0, valType.NumField())
-		for i := 0; i < valType.NumField(); i++ {
-			refField := valType.Field(i)
-			fieldId := parseFieldId(refField)
-			if fieldId == -1 {
-				continue
-			}
+		encoder := &structEncoder{}
+		extension.encoders[valType] = encoder
+		boundFields := boundFieldsOf(valType)
+		presenceOffset, hasPresence := presenceFieldOf(valType)
+		encoderFields := make([]structEncoderField, 0, len(boundFields))
+		fieldIds := make([]protocol.FieldId, 0, len(boundFields))
+		for _, boundField := range boundFields {
 			encoderField := structEncoderField{
-				offset:  refField.Offset,
-				fieldId: fieldId,
-				encoder: encoderOf(extension, prefix+" "+refField.Name, refField.Type),
+				offset:   boundField.offset,
+				embedded: boundField.embedded,
+				fieldId:  boundField.fieldId,
+				encoder: fieldEncoderOf(extension, prefix+" "+boundField.name,
+					boundField.valType, boundField.options),
+				valType: boundField.valType,
+				// with presence tracked, empty fields are written only if set
+				omitEmpty: hasPresence || extension.OmitPolicy == spi.OmitZero ||
+					spi.HasOption(boundField.options, "omitempty"),
+				omitNil: extension.OmitPolicy != spi.OmitNever,
 			}
 			encoderFields = append(encoderFields, encoderField)
+			fieldIds = append(fieldIds, boundField.fieldId)
 		}
-		return &structEncoder{
-			fields: encoderFields,
+		encoder.fields = encoderFields
+		encoder.fieldIds = fieldIds
+		encoder.unknownOffset, encoder.hasUnknown = unknownFieldOf(valType)
+		encoder.hasPresence = hasPresence
+		encoder.presenceOffset = presenceOffset
+		return encoder
+	case reflect.Interface:
+		if valType.NumMethod() == 0 {
+			return &interfaceEncoder{extension: extension}
 		}
 	case reflect.Ptr:
+		encoder := &pointerEncoder{valType: valType.Elem()}
+		extension.encoders[valType] = encoder
+		encoder.valEncoder = encoderOf(extension, prefix+" [ptrElem]", valType.Elem())
+		return encoder
+	}
+	return &unknownEncoder{prefix, valType}
+}
+
+// fieldEncoderOf gives extensions implementing spi.FieldExtension a chance to
+// encode the field according to its tag options
+func fieldEncoderOf(extension *Extension, prefix string, valType reflect.Type, options []string) internalEncoder {
+	if len(options) == 0 {
+		return encoderOf(extension, prefix, valType)
+	}
+	extEncoder := extension.FieldEncoderOf(valType, options)
+	if extEncoder != nil {
+		valObj := reflect.New(valType).Elem().Interface()
+		valEmptyInterface := *(*emptyInterface)(unsafe.Pointer(&valObj))
+		return &internalEncoderAdapter{valEmptyInterface: valEmptyInterface, encoder: extEncoder}
+	}
+	if valType.Kind() == reflect.Ptr {
 		return &pointerEncoder{
 			valType:    valType.Elem(),
-			valEncoder: encoderOf(extension, prefix+" [ptrElem]", valType.Elem()),
+			valEncoder: fieldEncoderOf(extension, prefix+" [ptrElem]", valType.Elem(), options),
 		}
 	}
-	return &unknownEncoder{prefix, valType}
+	return encoderOf(extension, prefix, valType)
 }
 
 type unknownEncoder struct {
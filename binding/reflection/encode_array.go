@@ -0,0 +1,59 @@
+package reflection
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"reflect"
+	"unsafe"
+)
+
+type arrayEncoder struct {
+	arrayType   reflect.Type
+	elemType    reflect.Type
+	elemEncoder internalEncoder
+}
+
+func (encoder *arrayEncoder) encode(ptr unsafe.Pointer, stream spi.Stream) {
+	length := encoder.arrayType.Len()
+	elemType := encoder.elemEncoder.thriftType()
+	ifaceEncoder, isInterface := encoder.elemEncoder.(*interfaceEncoder)
+	if isInterface {
+		elemType = ifaceEncoder.elemTypeOf(length, func() interface{} {
+			return *(*interface{})(ptr)
+		})
+	}
+	stream.WriteListHeader(elemType, length)
+	offset := uintptr(0)
+	for i := 0; i < length; i++ {
+		addr := unsafe.Pointer(uintptr(ptr) + offset)
+		offset += encoder.elemType.Size()
+		if isInterface {
+			if !ifaceEncoder.encodeElement(elemType, *(*interface{})(addr), stream) {
+				return
+			}
+			continue
+		}
+		if encoder.elemType.Kind() == reflect.Map {
+			addr = *(*unsafe.Pointer)(addr)
+		}
+		encoder.elemEncoder.encode(addr, stream)
+	}
+}
+
+func (encoder *arrayEncoder) thriftType() protocol.TType {
+	return protocol.TypeList
+}
+
+// byteArrayEncoder encodes [N]byte as binary
+type byteArrayEncoder struct {
+	arrayType reflect.Type
+}
+
+func (encoder *byteArrayEncoder) encode(ptr unsafe.Pointer, stream spi.Stream) {
+	length := encoder.arrayType.Len()
+	stream.WriteBinary(*(*[]byte)(unsafe.Pointer(&sliceHeader{ptr, length, length})))
+}
+
+func (encoder *byteArrayEncoder) thriftType() protocol.TType {
+	return protocol.TypeString
+}
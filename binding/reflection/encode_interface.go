@@ -0,0 +1,75 @@
+package reflection
+
+import (
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// interfaceEncoder encodes interface{} by the encoder of its dynamic type,
+// which is normally general objects like general.Struct
+type interfaceEncoder struct {
+	extension *Extension
+	encoders  sync.Map
+}
+
+func (encoder *interfaceEncoder) encode(ptr unsafe.Pointer, stream spi.Stream) {
+	encoder.encodeInterface(*(*interface{})(ptr), stream)
+}
+
+func (encoder *interfaceEncoder) encodeInterface(obj interface{}, stream spi.Stream) {
+	if obj == nil {
+		stream.ReportError("encode interface{}", "can not encode nil")
+		return
+	}
+	encoder.encoderOf(reflect.TypeOf(obj)).Encode(obj, stream)
+}
+
+func (encoder *interfaceEncoder) thriftType() protocol.TType {
+	return protocol.TypeStop
+}
+
+func (encoder *interfaceEncoder) dynamicThriftType(obj interface{}) protocol.TType {
+	if obj == nil {
+		return protocol.TypeStop
+	}
+	return encoder.encoderOf(reflect.TypeOf(obj)).ThriftType()
+}
+
+// elemTypeOf decides the element type in the header of list or map, from the first element.
+// Empty container is written as of i64 like general.List, as thrift has no type for unknown
+func (encoder *interfaceEncoder) elemTypeOf(length int, first func() interface{}) protocol.TType {
+	if length == 0 {
+		return protocol.TypeI64
+	}
+	return encoder.dynamicThriftType(first())
+}
+
+// encodeElement encodes the element of list or map, which must match the type in the header written already
+func (encoder *interfaceEncoder) encodeElement(elemType protocol.TType, obj interface{}, stream spi.Stream) bool {
+	if obj == nil {
+		stream.ReportError("encode interface{}", "can not encode nil")
+		return false
+	}
+	valEncoder := encoder.encoderOf(reflect.TypeOf(obj))
+	if valEncoder.ThriftType() != elemType {
+		stream.ReportError("encode interface{}", fmt.Sprintf(
+			"expected element of type %v but got %v", elemType, valEncoder.ThriftType()))
+		return false
+	}
+	valEncoder.Encode(obj, stream)
+	return true
+}
+
+func (encoder *interfaceEncoder) encoderOf(valType reflect.Type) spi.ValEncoder {
+	valEncoder, found := encoder.encoders.Load(valType)
+	if found {
+		return valEncoder.(spi.ValEncoder)
+	}
+	newEncoder := EncoderOf(encoder.extension, valType)
+	encoder.encoders.Store(valType, newEncoder)
+	return newEncoder
+}
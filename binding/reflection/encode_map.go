@@ -11,6 +11,10 @@ type mapEncoder struct {
 	mapInterface emptyInterface
 	keyEncoder   internalEncoder
 	elemEncoder  internalEncoder
+	// keyIndirect and elemIndirect tell the value is stored in interface itself,
+	// the encoder needs the address of it
+	keyIndirect  bool
+	elemIndirect bool
 }
 
 func (encoder *mapEncoder) encode(ptr unsafe.Pointer, stream spi.Stream) {
@@ -19,16 +23,54 @@ func (encoder *mapEncoder) encode(ptr unsafe.Pointer, stream spi.Stream) {
 	realInterface := (*interface{})(unsafe.Pointer(&mapInterface))
 	mapVal := reflect.ValueOf(*realInterface)
 	keys := mapVal.MapKeys()
-	stream.WriteMapHeader(encoder.keyEncoder.thriftType(), encoder.elemEncoder.thriftType(), len(keys))
+	keyType := encoder.keyEncoder.thriftType()
+	elemType := encoder.elemEncoder.thriftType()
+	if keyEncoder, isInterface := encoder.keyEncoder.(*interfaceEncoder); isInterface {
+		keyType = keyEncoder.elemTypeOf(len(keys), func() interface{} {
+			return keys[0].Interface()
+		})
+	}
+	if elemEncoder, isInterface := encoder.elemEncoder.(*interfaceEncoder); isInterface {
+		elemType = elemEncoder.elemTypeOf(len(keys), func() interface{} {
+			return mapVal.MapIndex(keys[0]).Interface()
+		})
+	}
+	stream.WriteMapHeader(keyType, elemType, len(keys))
 	for _, key := range keys {
-		keyObj := key.Interface()
-		keyInf := (*emptyInterface)(unsafe.Pointer(&keyObj))
-		encoder.keyEncoder.encode(keyInf.word, stream)
-		elem := mapVal.MapIndex(key)
-		elemObj := elem.Interface()
-		elemInf := (*emptyInterface)(unsafe.Pointer(&elemObj))
-		encoder.elemEncoder.encode(elemInf.word, stream)
+		if !encodeMapValue(encoder.keyEncoder, keyType, key.Interface(), encoder.keyIndirect, stream) {
+			return
+		}
+		if !encodeMapValue(encoder.elemEncoder, elemType, mapVal.MapIndex(key).Interface(), encoder.elemIndirect, stream) {
+			return
+		}
+	}
+}
+
+// encodeMapValue tells if the key or elem is encoded, the interface{} not matching ttype in header is not
+func encodeMapValue(encoder internalEncoder, ttype protocol.TType, obj interface{}, indirect bool, stream spi.Stream) bool {
+	if ifaceEncoder, isInterface := encoder.(*interfaceEncoder); isInterface {
+		return ifaceEncoder.encodeElement(ttype, obj, stream)
+	}
+	word := (*emptyInterface)(unsafe.Pointer(&obj)).word
+	if indirect {
+		encoder.encode(unsafe.Pointer(&word), stream)
+		return true
+	}
+	encoder.encode(word, stream)
+	return true
+}
+
+// isPointerShaped tells if the value of valType is stored in interface directly instead of by pointer
+func isPointerShaped(valType reflect.Type) bool {
+	switch valType.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Array:
+		return valType.Len() == 1 && isPointerShaped(valType.Elem())
+	case reflect.Struct:
+		return valType.NumField() == 1 && isPointerShaped(valType.Field(0).Type)
 	}
+	return false
 }
 
 func (encoder *mapEncoder) thriftType() protocol.TType {
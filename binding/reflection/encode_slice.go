@@ -15,16 +15,28 @@ type sliceEncoder struct {
 
 func (encoder *sliceEncoder) encode(ptr unsafe.Pointer, stream spi.Stream) {
 	slice := (*sliceHeader)(ptr)
-	stream.WriteListHeader(encoder.elemEncoder.thriftType(), slice.Len)
-	offset := uintptr(slice.Data)
-	var addr unsafe.Pointer
+	elemType := encoder.elemEncoder.thriftType()
+	ifaceEncoder, isInterface := encoder.elemEncoder.(*interfaceEncoder)
+	if isInterface {
+		elemType = ifaceEncoder.elemTypeOf(slice.Len, func() interface{} {
+			return *(*interface{})(slice.Data)
+		})
+	}
+	stream.WriteListHeader(elemType, slice.Len)
+	offset := uintptr(0)
 	for i := 0; i < slice.Len; i++ {
-		addr = unsafe.Pointer(offset)
+		addr := unsafe.Pointer(uintptr(slice.Data) + offset)
+		offset += encoder.elemType.Size()
+		if isInterface {
+			if !ifaceEncoder.encodeElement(elemType, *(*interface{})(addr), stream) {
+				return
+			}
+			continue
+		}
 		if encoder.elemType.Kind() == reflect.Map {
-			addr = unsafe.Pointer((uintptr)(*(*uint64)(addr)))
+			addr = *(*unsafe.Pointer)(addr)
 		}
 		encoder.elemEncoder.encode(addr, stream)
-		offset += encoder.elemType.Size()
 	}
 }
 
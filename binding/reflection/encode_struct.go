@@ -2,41 +2,115 @@ package reflection
 
 import (
 	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
 	"github.com/batchcorp/thrift-iterator/spi"
+	"reflect"
 	"unsafe"
 )
 
 type structEncoder struct {
-	fields []structEncoderField
+	fields         []structEncoderField
+	fieldIds       []protocol.FieldId
+	hasUnknown     bool
+	unknownOffset  uintptr
+	hasPresence    bool
+	presenceOffset uintptr
 }
 
 type structEncoderField struct {
-	offset  uintptr
-	fieldId protocol.FieldId
-	encoder internalEncoder
+	offset    uintptr
+	embedded  []embeddedPointer
+	fieldId   protocol.FieldId
+	encoder   internalEncoder
+	valType   reflect.Type
+	omitEmpty bool
+	omitNil   bool
 }
 
 func (encoder *structEncoder) encode(ptr unsafe.Pointer, stream spi.Stream) {
 	stream.WriteStructHeader()
+	if stream.Error() != nil {
+		// nested too deep, the value might be cyclic
+		return
+	}
+	var presence *protocol.Presence
+	if encoder.hasPresence {
+		presence = (*protocol.Presence)(unsafe.Pointer(uintptr(ptr) + encoder.presenceOffset))
+	}
 	for _, field := range encoder.fields {
-		fieldPtr := unsafe.Pointer(uintptr(ptr) + field.offset)
-		switch field.encoder.(type) {
-		case *pointerEncoder, *sliceEncoder:
+		fieldPtr := field.fieldPtr(ptr)
+		if fieldPtr == nil {
+			continue
+		}
+		// with presence tracked, exactly the fields set are written
+		if presence != nil && !presence.IsSet(field.fieldId) {
+			continue
+		}
+		if presence == nil && field.omitEmpty && isEmptyValue(field.valType, fieldPtr) {
+			continue
+		}
+		fieldType := field.encoder.thriftType()
+		switch fieldEncoder := field.encoder.(type) {
+		case *pointerEncoder:
 			if *(*unsafe.Pointer)(fieldPtr) == nil {
 				continue
 			}
+		case *sliceEncoder:
+			if presence == nil && field.omitNil && *(*unsafe.Pointer)(fieldPtr) == nil {
+				continue
+			}
 		case *mapEncoder:
-			if *(*unsafe.Pointer)(fieldPtr) == nil {
+			if presence == nil && field.omitNil && *(*unsafe.Pointer)(fieldPtr) == nil {
 				continue
 			}
 			fieldPtr = *(*unsafe.Pointer)(fieldPtr)
+		case *interfaceEncoder:
+			if (*emptyInterface)(fieldPtr).typ == nil {
+				continue
+			}
+			fieldType = fieldEncoder.dynamicThriftType(*(*interface{})(fieldPtr))
 		}
-		stream.WriteStructField(field.encoder.thriftType(), field.fieldId)
+		stream.WriteStructField(fieldType, field.fieldId)
 		field.encoder.encode(fieldPtr, stream)
 	}
+	if encoder.hasUnknown {
+		unknown := *(*raw.Struct)(unsafe.Pointer(uintptr(ptr) + encoder.unknownOffset))
+		unknown.WriteFields(stream, encoder.fieldIds...)
+	}
 	stream.WriteStructFieldStop()
 }
 
 func (encoder *structEncoder) thriftType() protocol.TType {
 	return protocol.TypeStruct
-}
\ No newline at end of file
+}
+
+// fieldPtr returns nil if the field is promoted from a nil embedded pointer
+func (field *structEncoderField) fieldPtr(ptr unsafe.Pointer) unsafe.Pointer {
+	for _, embedded := range field.embedded {
+		ptr = *(*unsafe.Pointer)(unsafe.Pointer(uintptr(ptr) + embedded.offset))
+		if ptr == nil {
+			return nil
+		}
+	}
+	return unsafe.Pointer(uintptr(ptr) + field.offset)
+}
+
+// isEmptyValue follows the omitempty rule of encoding/json
+func isEmptyValue(valType reflect.Type, ptr unsafe.Pointer) bool {
+	val := reflect.NewAt(valType, ptr).Elem()
+	switch val.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return val.Len() == 0
+	case reflect.Bool:
+		return !val.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return val.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return val.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return val.IsNil()
+	}
+	return false
+}
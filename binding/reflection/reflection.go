@@ -0,0 +1,51 @@
+package reflection
+
+import (
+	"github.com/batchcorp/thrift-iterator/spi"
+	"reflect"
+)
+
+// Extension carries user provided extension and binding config through reflection binding
+type Extension struct {
+	spi.Extension
+	OmitPolicy spi.OmitPolicy
+	// ReuseValues makes decoders reuse the pointers, slices and maps already in the value
+	ReuseValues bool
+	// StringPolicy decides how interface{} fields decode TypeString
+	StringPolicy spi.StringPolicy
+	// decoders and encoders being built, the composite codec is registered before its elements,
+	// so that recursive types refer back to it instead of recursing forever
+	decoders map[reflect.Type]internalDecoder
+	encoders map[reflect.Type]internalEncoder
+}
+
+func extensionOf(extension spi.Extension) *Extension {
+	if reflectionExtension, isReflectionExtension := extension.(*Extension); isReflectionExtension {
+		return reflectionExtension
+	}
+	return &Extension{Extension: extension}
+}
+
+// forBuilding copies the extension with its own registry of codecs being built
+func (ext *Extension) forBuilding() *Extension {
+	copied := *ext
+	copied.decoders = map[reflect.Type]internalDecoder{}
+	copied.encoders = map[reflect.Type]internalEncoder{}
+	return &copied
+}
+
+func (ext *Extension) FieldDecoderOf(valType reflect.Type, options []string) spi.ValDecoder {
+	fieldExtension, isFieldExtension := ext.Extension.(spi.FieldExtension)
+	if !isFieldExtension || len(options) == 0 {
+		return nil
+	}
+	return fieldExtension.FieldDecoderOf(valType, options)
+}
+
+func (ext *Extension) FieldEncoderOf(valType reflect.Type, options []string) spi.ValEncoder {
+	fieldExtension, isFieldExtension := ext.Extension.(spi.FieldExtension)
+	if !isFieldExtension || len(options) == 0 {
+		return nil
+	}
+	return fieldExtension.FieldEncoderOf(valType, options)
+}
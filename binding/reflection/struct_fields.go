@@ -0,0 +1,123 @@
+package reflection
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"reflect"
+	"strings"
+)
+
+var rawStructType = reflect.TypeOf(raw.Struct(nil))
+var presenceType = reflect.TypeOf(protocol.Presence{})
+
+// embeddedPointer is an anonymous *Struct field on the path to a promoted field
+type embeddedPointer struct {
+	offset  uintptr
+	valType reflect.Type
+}
+
+type boundField struct {
+	name     string
+	fieldId  protocol.FieldId
+	options  []string
+	valType  reflect.Type
+	offset   uintptr
+	embedded []embeddedPointer
+	depth    int
+}
+
+// boundFieldsOf lists the tagged fields of struct, including those promoted from
+// anonymous struct fields. like encoding/json, the shallowest field wins if the
+// field id is used more than once, and fields of same depth hide each other.
+func boundFieldsOf(valType reflect.Type) []boundField {
+	fields := collectBoundFields(valType, nil, 0, 0, map[reflect.Type]bool{})
+	depths := map[protocol.FieldId]int{}
+	counts := map[protocol.FieldId]int{}
+	for _, field := range fields {
+		depth, found := depths[field.fieldId]
+		if !found || field.depth < depth {
+			depths[field.fieldId] = field.depth
+			counts[field.fieldId] = 1
+		} else if field.depth == depth {
+			counts[field.fieldId]++
+		}
+	}
+	dominantFields := make([]boundField, 0, len(fields))
+	for _, field := range fields {
+		if field.depth == depths[field.fieldId] && counts[field.fieldId] == 1 {
+			dominantFields = append(dominantFields, field)
+		}
+	}
+	return dominantFields
+}
+
+func collectBoundFields(valType reflect.Type, embedded []embeddedPointer, baseOffset uintptr,
+	depth int, visiting map[reflect.Type]bool) []boundField {
+	if visiting[valType] {
+		return nil
+	}
+	visiting[valType] = true
+	defer delete(visiting, valType)
+	var fields []boundField
+	for i := 0; i < valType.NumField(); i++ {
+		refField := valType.Field(i)
+		if refField.Type == presenceType {
+			continue
+		}
+		if refField.Anonymous && refField.Tag.Get("thrift") == "" {
+			embeddedType := refField.Type
+			if embeddedType.Kind() == reflect.Struct {
+				fields = append(fields, collectBoundFields(embeddedType, embedded,
+					baseOffset+refField.Offset, depth+1, visiting)...)
+				continue
+			}
+			if embeddedType.Kind() == reflect.Ptr && embeddedType.Elem().Kind() == reflect.Struct {
+				pathToElem := append(append([]embeddedPointer{}, embedded...), embeddedPointer{
+					offset:  baseOffset + refField.Offset,
+					valType: embeddedType.Elem(),
+				})
+				fields = append(fields, collectBoundFields(embeddedType.Elem(), pathToElem,
+					0, depth+1, visiting)...)
+				continue
+			}
+		}
+		fieldId, options := parseFieldTag(refField)
+		if fieldId == -1 {
+			continue
+		}
+		fields = append(fields, boundField{
+			name:     refField.Name,
+			fieldId:  fieldId,
+			options:  options,
+			valType:  refField.Type,
+			offset:   baseOffset + refField.Offset,
+			embedded: embedded,
+			depth:    depth,
+		})
+	}
+	return fields
+}
+
+// unknownFieldOf finds the raw.Struct field tagged `thrift:",unknown"`,
+// which keeps the fields not bound to go struct fields
+func unknownFieldOf(valType reflect.Type) (offset uintptr, found bool) {
+	for i := 0; i < valType.NumField(); i++ {
+		refField := valType.Field(i)
+		parts := strings.Split(refField.Tag.Get("thrift"), ",")
+		if len(parts) >= 2 && parts[1] == "unknown" && refField.Type == rawStructType {
+			return refField.Offset, true
+		}
+	}
+	return 0, false
+}
+
+// presenceFieldOf finds the protocol.Presence field tracking which fields are set
+func presenceFieldOf(valType reflect.Type) (offset uintptr, found bool) {
+	for i := 0; i < valType.NumField(); i++ {
+		refField := valType.Field(i)
+		if refField.Type == presenceType {
+			return refField.Offset, true
+		}
+	}
+	return 0, false
+}
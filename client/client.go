@@ -0,0 +1,259 @@
+// Package client calls thrift services with go structs as arguments and results,
+// connections are pooled, or shared by concurrent calls if the server supports pipelining
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/middleware"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/transport"
+	"net"
+	"sync"
+)
+
+// ErrClientClosed is returned by calls made after Close
+var ErrClientClosed = errors.New("thrifter: client closed")
+
+type Config struct {
+	// API decides the protocol, thrifter.DefaultConfig if nil
+	API    thrifter.API
+	Framed bool
+	// Addr is dialed with tcp if Dial is not set
+	Addr string
+	Dial func(ctx context.Context) (net.Conn, error)
+	// MaxIdleConns is the number of connections kept in pool, 2 if not set
+	MaxIdleConns int
+	// MaxConns limits the connections used at the same time, unlimited if not set
+	MaxConns int
+	// Pipelined sends concurrent calls on one connection without waiting for the replies,
+	// which are matched by seqid. The server must read next call before replying previous one
+	Pipelined bool
+	// Interceptors process the calls before sending, the args of call is the args passed to Call.
+	// The seqid is not assigned yet when interceptors are called
+	Interceptors []middleware.Interceptor
+}
+
+type Client struct {
+	cfg       Config
+	api       thrifter.API
+	semaphore chan struct{}
+	mu        sync.Mutex
+	idle      []*clientConn
+	pipeline  *pipelinedConn
+	closed    bool
+}
+
+type clientConn struct {
+	netConn net.Conn
+	conn    *transport.Conn
+	seqId   protocol.SeqId
+}
+
+func New(cfg Config) *Client {
+	api := cfg.API
+	if api == nil {
+		api = thrifter.DefaultConfig
+	}
+	if cfg.Dial == nil {
+		addr := cfg.Addr
+		cfg.Dial = func(ctx context.Context) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "tcp", addr)
+		}
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = 2
+	}
+	client := &Client{cfg: cfg, api: api}
+	if cfg.MaxConns > 0 {
+		client.semaphore = make(chan struct{}, cfg.MaxConns)
+	}
+	return client
+}
+
+// Call sends args as the arguments struct of method, and decodes the reply into result,
+// which should be a pointer to the result struct, or nil to ignore the reply.
+// Exception replied by server is returned as *protocol.ApplicationException
+func (client *Client) Call(ctx context.Context, method string, args interface{}, result interface{}) error {
+	return client.call(ctx, method, args, result, false)
+}
+
+// CallOneway sends args without waiting for reply
+func (client *Client) CallOneway(ctx context.Context, method string, args interface{}) error {
+	return client.call(ctx, method, args, nil, true)
+}
+
+func (client *Client) call(ctx context.Context, method string, args interface{}, result interface{}, oneway bool) error {
+	messageType := protocol.MessageTypeCall
+	if oneway {
+		messageType = protocol.MessageTypeOneWay
+	}
+	if len(client.cfg.Interceptors) == 0 {
+		return client.send(ctx, method, args, result, oneway)
+	}
+	call := middleware.NewCall(client.api, protocol.MessageHeader{
+		MessageName: method,
+		MessageType: messageType,
+	}, args)
+	handler := middleware.Wrap(func(ctx context.Context, call *middleware.Call) (interface{}, error) {
+		return result, client.send(ctx, call.Header.MessageName, call.Args(), result, oneway)
+	}, client.cfg.Interceptors...)
+	_, err := handler(ctx, call)
+	return err
+}
+
+func (client *Client) send(ctx context.Context, method string, args interface{}, result interface{}, oneway bool) error {
+	if client.cfg.Pipelined {
+		return client.callPipelined(ctx, method, args, result, oneway)
+	}
+	return client.callPooled(ctx, method, args, result, oneway)
+}
+
+// Close closes idle connections and the pipelined connection, calls in progress on pooled connections
+// are not interrupted, but their connections are closed afterwards
+func (client *Client) Close() error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.closed = true
+	for _, cc := range client.idle {
+		cc.conn.Close()
+	}
+	client.idle = nil
+	if client.pipeline != nil {
+		client.pipeline.close(ErrClientClosed)
+		client.pipeline = nil
+	}
+	return nil
+}
+
+func (client *Client) dial(ctx context.Context) (*clientConn, error) {
+	netConn, err := client.cfg.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &clientConn{
+		netConn: netConn,
+		conn:    transport.NewConn(client.api, netConn, client.cfg.Framed),
+	}, nil
+}
+
+func (client *Client) callPooled(ctx context.Context, method string, args interface{}, result interface{}, oneway bool) error {
+	if client.semaphore != nil {
+		select {
+		case client.semaphore <- struct{}{}:
+			defer func() { <-client.semaphore }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	cc, err := client.getConn(ctx)
+	if err != nil {
+		return err
+	}
+	reusable, err := cc.roundTrip(ctx, method, args, result, oneway)
+	if !reusable {
+		cc.conn.Close()
+		return err
+	}
+	client.putConn(cc)
+	return err
+}
+
+func (client *Client) getConn(ctx context.Context) (*clientConn, error) {
+	client.mu.Lock()
+	if client.closed {
+		client.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+	if len(client.idle) > 0 {
+		cc := client.idle[len(client.idle)-1]
+		client.idle = client.idle[:len(client.idle)-1]
+		client.mu.Unlock()
+		return cc, nil
+	}
+	client.mu.Unlock()
+	return client.dial(ctx)
+}
+
+func (client *Client) putConn(cc *clientConn) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.closed || len(client.idle) >= client.cfg.MaxIdleConns {
+		cc.conn.Close()
+		return
+	}
+	client.idle = append(client.idle, cc)
+}
+
+// roundTrip tells if the connection can be reused, which is false if the error is not replied by server
+func (cc *clientConn) roundTrip(ctx context.Context, method string, args interface{}, result interface{}, oneway bool) (reusable bool, err error) {
+	stop, err := transport.WatchContext(ctx, cc.netConn)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		stop()
+		if !reusable && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}()
+	cc.seqId++
+	if err = writeCall(cc.conn, method, cc.seqId, args, oneway); err != nil || oneway {
+		return err == nil, err
+	}
+	header, err := cc.conn.ReadMessageHeader()
+	if err != nil {
+		return false, err
+	}
+	if header.SeqId != cc.seqId {
+		return false, protocol.NewApplicationException(protocol.ExceptionBadSequenceId,
+			fmt.Sprintf("%s: expect seqid %d, got %d", method, cc.seqId, header.SeqId))
+	}
+	return readReply(cc.conn, header, method, result)
+}
+
+func writeCall(conn *transport.Conn, method string, seqId protocol.SeqId, args interface{}, oneway bool) error {
+	return conn.WriteMessage(callHeader(method, seqId, oneway), args)
+}
+
+func callHeader(method string, seqId protocol.SeqId, oneway bool) protocol.MessageHeader {
+	messageType := protocol.MessageTypeCall
+	if oneway {
+		messageType = protocol.MessageTypeOneWay
+	}
+	return protocol.MessageHeader{
+		MessageName: method,
+		MessageType: messageType,
+		SeqId:       seqId,
+	}
+}
+
+// readReply tells if the connection can be reused, which is false if the reply is not read fully
+func readReply(conn *transport.Conn, header protocol.MessageHeader, method string, result interface{}) (bool, error) {
+	switch header.MessageType {
+	case protocol.MessageTypeException:
+		exception := &protocol.ApplicationException{}
+		if err := conn.ReadMessageBody(exception); err != nil {
+			return false, err
+		}
+		return true, exception
+	case protocol.MessageTypeReply:
+		if header.MessageName != method {
+			return false, protocol.NewApplicationException(protocol.ExceptionWrongMethodName,
+				fmt.Sprintf("%s: unexpected reply of %s", method, header.MessageName))
+		}
+		if result == nil {
+			var discarded raw.Struct
+			err := conn.ReadMessageBody(&discarded)
+			return err == nil, err
+		}
+		err := conn.ReadMessageBody(result)
+		return err == nil, err
+	}
+	return false, protocol.NewApplicationException(protocol.ExceptionInvalidMessageType,
+		fmt.Sprintf("%s: unexpected message type %d", method, header.MessageType))
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"sync"
+)
+
+// pipelinedConn is shared by concurrent calls, one goroutine reads the replies
+// and hands them to the calls waiting for the same seqid
+type pipelinedConn struct {
+	*clientConn
+	mu      sync.Mutex
+	pending map[protocol.SeqId]*pendingCall
+	err     error
+}
+
+type pendingCall struct {
+	method string
+	result interface{}
+	done   chan error
+}
+
+func (client *Client) callPipelined(ctx context.Context, method string, args interface{}, result interface{}, oneway bool) error {
+	pc, err := client.getPipeline(ctx)
+	if err != nil {
+		return err
+	}
+	seqId, call, err := pc.register(method, result, oneway)
+	if err != nil {
+		return err
+	}
+	// the write is bounded by ctx, a stalled server would block the calls waiting to write as well
+	if err := pc.conn.WriteMessageContext(ctx, callHeader(method, seqId, oneway), args); err != nil {
+		if err == ctx.Err() {
+			// nothing written, the connection is still fine for other calls
+			pc.unregister(seqId)
+			return err
+		}
+		pc.close(err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	if oneway {
+		return nil
+	}
+	select {
+	case err := <-call.done:
+		return err
+	case <-ctx.Done():
+		if pc.unregister(seqId) {
+			return ctx.Err()
+		}
+		// the reply is being decoded into result
+		return <-call.done
+	}
+}
+
+func (client *Client) getPipeline(ctx context.Context) (*pipelinedConn, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.closed {
+		return nil, ErrClientClosed
+	}
+	if client.pipeline != nil && client.pipeline.broken() == nil {
+		return client.pipeline, nil
+	}
+	cc, err := client.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc := &pipelinedConn{clientConn: cc, pending: map[protocol.SeqId]*pendingCall{}}
+	go pc.readReplies()
+	client.pipeline = pc
+	return pc, nil
+}
+
+func (pc *pipelinedConn) register(method string, result interface{}, oneway bool) (protocol.SeqId, *pendingCall, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.err != nil {
+		return 0, nil, pc.err
+	}
+	pc.seqId++
+	if oneway {
+		return pc.seqId, nil, nil
+	}
+	call := &pendingCall{method: method, result: result, done: make(chan error, 1)}
+	pc.pending[pc.seqId] = call
+	return pc.seqId, call, nil
+}
+
+// unregister returns false if the reply has been taken by the reading goroutine
+func (pc *pipelinedConn) unregister(seqId protocol.SeqId) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	_, found := pc.pending[seqId]
+	delete(pc.pending, seqId)
+	return found
+}
+
+func (pc *pipelinedConn) take(seqId protocol.SeqId) *pendingCall {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	call := pc.pending[seqId]
+	delete(pc.pending, seqId)
+	return call
+}
+
+func (pc *pipelinedConn) broken() error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.err
+}
+
+// close fails all pending calls with err
+func (pc *pipelinedConn) close(err error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.err != nil {
+		return
+	}
+	pc.err = err
+	pc.conn.Close()
+	for seqId, call := range pc.pending {
+		call.done <- err
+		delete(pc.pending, seqId)
+	}
+}
+
+func (pc *pipelinedConn) readReplies() {
+	for {
+		header, err := pc.conn.ReadMessageHeader()
+		if err != nil {
+			pc.close(err)
+			return
+		}
+		call := pc.take(header.SeqId)
+		if call == nil {
+			// the call has been cancelled
+			var discarded raw.Struct
+			if err := pc.conn.ReadMessageBody(&discarded); err != nil {
+				pc.close(err)
+				return
+			}
+			continue
+		}
+		reusable, err := readReply(pc.conn, header, call.method, call.result)
+		call.done <- err
+		if !reusable {
+			pc.close(err)
+			return
+		}
+	}
+}
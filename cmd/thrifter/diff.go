@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// runDiff compares two encoded messages, the format of each file is detected from its leading bytes.
+// It exits with 1 if they differ, like diff(1)
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: thrifter diff a.bin b.bin")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	diffs, err := diffFiles(flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	printDiffs(os.Stdout, diffs)
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+}
+
+type encodedMessage struct {
+	header    protocol.MessageHeader
+	arguments raw.Struct
+	format    thrifter.Format
+}
+
+func diffFiles(oldFile string, newFile string) ([]string, error) {
+	old, err := readMessage(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	new, err := readMessage(newFile)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []string
+	if old.header.MessageName != new.header.MessageName {
+		diffs = append(diffs, fmt.Sprintf("~ name %q -> %q", old.header.MessageName, new.header.MessageName))
+	}
+	if old.header.MessageType != new.header.MessageType {
+		diffs = append(diffs, fmt.Sprintf("~ type %v -> %v", old.header.MessageType, new.header.MessageType))
+	}
+	if old.header.SeqId != new.header.SeqId {
+		diffs = append(diffs, fmt.Sprintf("~ seqid %v -> %v", old.header.SeqId, new.header.SeqId))
+	}
+	argDiffs, err := diffArguments(old, new)
+	if err != nil {
+		return nil, err
+	}
+	for _, diff := range argDiffs {
+		diffs = append(diffs, diff.String())
+	}
+	return diffs, nil
+}
+
+// diffArguments compares the raw fields if both are of same protocol, otherwise decodes them fully
+func diffArguments(old *encodedMessage, new *encodedMessage) ([]general.Difference, error) {
+	oldAPI := thrifter.Config{Protocol: old.format.Protocol, StringPolicy: spi.BinaryIfInvalidUTF8}.Froze()
+	if old.format.Protocol == new.format.Protocol {
+		return oldAPI.DiffRaw(old.arguments, new.arguments)
+	}
+	newAPI := thrifter.Config{Protocol: new.format.Protocol, StringPolicy: spi.BinaryIfInvalidUTF8}.Froze()
+	oldArgs, err := decodeArguments(oldAPI, old.arguments)
+	if err != nil {
+		return nil, err
+	}
+	newArgs, err := decodeArguments(newAPI, new.arguments)
+	if err != nil {
+		return nil, err
+	}
+	return general.Diff(oldArgs, newArgs), nil
+}
+
+func decodeArguments(api thrifter.API, args raw.Struct) (general.Struct, error) {
+	encoded, err := api.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	var decoded general.Struct
+	err = api.Unmarshal(encoded, &decoded)
+	return decoded, err
+}
+
+func readMessage(file string) (*encodedMessage, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	decoder := thrifter.NewAutoDecoder(bytes.NewReader(buf))
+	msg := &encodedMessage{}
+	if err := decoder.Decode(&msg.header); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := decoder.Decode(&msg.arguments); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	msg.format = decoder.Format()
+	return msg, nil
+}
+
+func printDiffs(writer io.Writer, diffs []string) {
+	for _, diff := range diffs {
+		fmt.Fprintln(writer, diff)
+	}
+}
@@ -7,6 +7,10 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
 	pkgPath := flag.String("pkg", "", "the package to generate generic code for")
 	flag.Parse()
 	if *pkgPath == "" {
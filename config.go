@@ -1,6 +1,7 @@
 package thrifter
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"github.com/batchcorp/thrift-iterator/binding/codegen"
@@ -18,13 +19,24 @@ import (
 )
 
 type frozenConfig struct {
-	extension     spi.Extension
-	protocol      Protocol
-	genDecoders   sync.Map
-	genEncoders   sync.Map
-	extDecoders   sync.Map
-	extEncoders   sync.Map
-	staticCodegen bool
+	extension      spi.Extensions
+	protocol       Protocol
+	genDecoders    sync.Map
+	genEncoders    sync.Map
+	extDecoders    sync.Map
+	extEncoders    sync.Map
+	staticCodegen  bool
+	omitPolicy     spi.OmitPolicy
+	compactVersion byte
+	zeroCopy       bool
+	zeroCopyString bool
+	maxDepth       int
+	reuseValues    bool
+	stringPolicy   spi.StringPolicy
+	streamPool     sync.Pool
+	iteratorPool   sync.Pool
+	// siblings are the configs of other protocols, used by the auto decoder
+	siblings sync.Map
 }
 
 func (cfg Config) AddExtension(extension spi.Extension) Config {
@@ -33,12 +45,19 @@ func (cfg Config) AddExtension(extension spi.Extension) Config {
 }
 
 func (cfg Config) Froze() API {
-	extensions := append(cfg.Extensions, &general.Extension{})
+	extensions := append(cfg.Extensions, &general.Extension{StringPolicy: cfg.StringPolicy})
 	extensions = append(extensions, &raw.Extension{})
 	api := &frozenConfig{
-		extension:     extensions,
-		protocol:      cfg.Protocol,
-		staticCodegen: cfg.StaticCodegen,
+		extension:      extensions,
+		protocol:       cfg.Protocol,
+		staticCodegen:  cfg.StaticCodegen,
+		omitPolicy:     cfg.OmitPolicy,
+		compactVersion: cfg.CompactVersion,
+		zeroCopy:       cfg.ZeroCopy,
+		zeroCopyString: cfg.ZeroCopyString,
+		maxDepth:       cfg.MaxDepth,
+		reuseValues:    cfg.ReuseValues,
+		stringPolicy:   cfg.StringPolicy,
 	}
 	api.extDecoders = sync.Map{}
 	api.genDecoders = sync.Map{}
@@ -73,6 +92,18 @@ func (cfg *frozenConfig) PrepareDecoder(valType reflect.Type) {
 	cfg.addGenDecoder(valType, decoder)
 }
 
+func (cfg *frozenConfig) PrepareFieldDecoder(valType reflect.Type, options ...string) {
+	cacheKey := spi.FieldCodecName(valType, options)
+	if cfg.GetDecoder(cacheKey) != nil {
+		return
+	}
+	decoder := cfg.extension.FieldDecoderOf(valType, options)
+	if decoder == nil {
+		decoder = cfg.extension.DecoderOf(valType)
+	}
+	cfg.addExtDecoder(cacheKey, decoder)
+}
+
 func (cfg *frozenConfig) GetDecoder(cacheKey string) spi.ValDecoder {
 	decoder, found := cfg.extDecoders.Load(cacheKey)
 	if found {
@@ -99,6 +130,18 @@ func (cfg *frozenConfig) PrepareEncoder(valType reflect.Type) {
 	cfg.addGenEncoder(valType, encoder)
 }
 
+func (cfg *frozenConfig) PrepareFieldEncoder(valType reflect.Type, options ...string) {
+	cacheKey := spi.FieldCodecName(valType, options)
+	if cfg.GetEncoder(cacheKey) != nil {
+		return
+	}
+	encoder := cfg.extension.FieldEncoderOf(valType, options)
+	if encoder == nil {
+		encoder = cfg.extension.EncoderOf(valType)
+	}
+	cfg.addExtEncoder(cacheKey, encoder)
+}
+
 func (cfg *frozenConfig) GetEncoder(cacheKey string) spi.ValEncoder {
 	encoder, found := cfg.extEncoders.Load(cacheKey)
 	if found {
@@ -118,9 +161,20 @@ func (cfg *frozenConfig) getGenEncoder(cacheKey reflect.Type) spi.ValEncoder {
 func (cfg *frozenConfig) NewStream(writer io.Writer, buf []byte) spi.Stream {
 	switch cfg.protocol {
 	case ProtocolBinary:
-		return binary.NewStream(cfg, writer, buf)
+		stream := binary.NewStream(cfg, writer, buf)
+		if cfg.maxDepth != 0 {
+			stream.SetMaxDepth(cfg.maxDepth)
+		}
+		return stream
 	case ProtocolCompact:
-		return compact.NewStream(cfg, writer, buf)
+		stream := compact.NewStream(cfg, writer, buf)
+		if cfg.compactVersion != 0 {
+			stream.SetVersion(cfg.compactVersion)
+		}
+		if cfg.maxDepth != 0 {
+			stream.SetMaxDepth(cfg.maxDepth)
+		}
+		return stream
 	}
 	panic("unsupported protocol")
 }
@@ -128,9 +182,22 @@ func (cfg *frozenConfig) NewStream(writer io.Writer, buf []byte) spi.Stream {
 func (cfg *frozenConfig) NewIterator(reader io.Reader, buf []byte) spi.Iterator {
 	switch cfg.protocol {
 	case ProtocolBinary:
-		return binary.NewIterator(cfg, reader, buf)
+		iter := binary.NewIterator(cfg, reader, buf)
+		iter.SetZeroCopy(cfg.zeroCopy, cfg.zeroCopyString)
+		if cfg.maxDepth != 0 {
+			iter.SetMaxDepth(cfg.maxDepth)
+		}
+		return iter
 	case ProtocolCompact:
-		return compact.NewIterator(cfg, reader, buf)
+		iter := compact.NewIterator(cfg, reader, buf)
+		if cfg.compactVersion != 0 {
+			iter.SetVersion(cfg.compactVersion)
+		}
+		iter.SetZeroCopy(cfg.zeroCopy, cfg.zeroCopyString)
+		if cfg.maxDepth != 0 {
+			iter.SetMaxDepth(cfg.maxDepth)
+		}
+		return iter
 	}
 	panic("unsupported protocol")
 }
@@ -166,7 +233,7 @@ func (cfg *frozenConfig) decoderOf(valType reflect.Type) spi.ValDecoder {
 	if cfg.staticCodegen {
 		return cfg.staticDecoderOf(valType)
 	}
-	return reflection.DecoderOf(cfg.extension, valType)
+	return reflection.DecoderOf(cfg.reflectionExtension(), valType)
 }
 
 func (cfg *frozenConfig) staticDecoderOf(valType reflect.Type) spi.ValDecoder {
@@ -175,7 +242,7 @@ func (cfg *frozenConfig) staticDecoderOf(valType reflect.Type) spi.ValDecoder {
 		iteratorType = reflect.TypeOf((*compact.Iterator)(nil))
 	}
 	funcObj := generic.Expand(codegen.Decode,
-		"EXT", &codegen.Extension{Extension: cfg.extension},
+		"EXT", cfg.codegenExtension(),
 		"ST", iteratorType,
 		"DT", valType)
 	f := funcObj.(func(interface{}, interface{}))
@@ -186,7 +253,7 @@ func (cfg *frozenConfig) encoderOf(valType reflect.Type) spi.ValEncoder {
 	if cfg.staticCodegen {
 		return cfg.staticEncoderOf(valType)
 	}
-	return reflection.EncoderOf(cfg.extension, valType)
+	return reflection.EncoderOf(cfg.reflectionExtension(), valType)
 }
 
 func (cfg *frozenConfig) staticEncoderOf(valType reflect.Type) spi.ValEncoder {
@@ -195,13 +262,22 @@ func (cfg *frozenConfig) staticEncoderOf(valType reflect.Type) spi.ValEncoder {
 		streamType = reflect.TypeOf((*compact.Stream)(nil))
 	}
 	funcObj := generic.Expand(codegen.Encode,
-		"EXT", &codegen.Extension{Extension: cfg.extension},
+		"EXT", cfg.codegenExtension(),
 		"ST", valType,
 		"DT", streamType)
 	f := funcObj.(func(interface{}, interface{}))
 	return &funcEncoder{f}
 }
 
+func (cfg *frozenConfig) reflectionExtension() *reflection.Extension {
+	return &reflection.Extension{Extension: cfg.extension, OmitPolicy: cfg.omitPolicy,
+		ReuseValues: cfg.reuseValues, StringPolicy: cfg.stringPolicy}
+}
+
+func (cfg *frozenConfig) codegenExtension() *codegen.Extension {
+	return &codegen.Extension{Extension: cfg.extension, OmitPolicy: cfg.omitPolicy, ReuseValues: cfg.reuseValues}
+}
+
 type funcDecoder struct {
 	f func(dst interface{}, src interface{})
 }
@@ -232,7 +308,8 @@ func (cfg *frozenConfig) Unmarshal(buf []byte, val interface{}) error {
 	if buf == nil {
 		return errors.New("empty input")
 	}
-	iter := cfg.NewIterator(nil, buf)
+	iter := cfg.BorrowIterator(nil, buf)
+	defer cfg.ReturnIterator(iter)
 	decoder.Decode(val, iter)
 	if iter.Error() != nil {
 		return iter.Error()
@@ -241,25 +318,30 @@ func (cfg *frozenConfig) Unmarshal(buf []byte, val interface{}) error {
 }
 
 func (cfg *frozenConfig) Marshal(val interface{}) ([]byte, error) {
+	return cfg.MarshalTo(nil, val)
+}
+
+func (cfg *frozenConfig) MarshalTo(dst []byte, val interface{}) ([]byte, error) {
 	valType := reflect.TypeOf(val)
 	encoder := cfg.getGenEncoder(valType)
 	if encoder == nil {
 		encoder = cfg.encoderOf(valType)
 		cfg.addGenEncoder(valType, encoder)
 	}
-	stream := cfg.NewStream(nil, nil)
+	stream := cfg.BorrowStream(nil)
+	defer cfg.ReturnStream(stream)
 	encoder.Encode(val, stream)
 	if stream.Error() != nil {
-		return nil, stream.Error()
+		return dst, stream.Error()
 	}
-	buf := stream.Buffer()
-	return buf, nil
+	return append(dst, stream.Buffer()...), nil
 }
 
 func (cfg *frozenConfig) NewDecoder(reader io.Reader, buf []byte) *Decoder {
 	return &Decoder{
-		cfg:  cfg,
-		iter: cfg.NewIterator(reader, buf),
+		cfg:    cfg,
+		iter:   cfg.NewIterator(reader, buf),
+		format: Format{Protocol: cfg.protocol},
 	}
 }
 
@@ -267,13 +349,77 @@ func (cfg *frozenConfig) NewEncoder(writer io.Writer) *Encoder {
 	return &Encoder{
 		cfg:    cfg,
 		stream: cfg.NewStream(writer, nil),
+		format: Format{Protocol: cfg.protocol},
+	}
+}
+
+func (cfg *frozenConfig) NewAutoDecoder(reader io.Reader) *Decoder {
+	return &Decoder{
+		cfg:    cfg,
+		reader: bufio.NewReader(reader),
+	}
+}
+
+func (cfg *frozenConfig) NewFormatEncoder(writer io.Writer, format Format) *Encoder {
+	if format.Protocol == 0 {
+		format.Protocol = cfg.protocol
+	}
+	if format.Header {
+		format.Framed = true
+	}
+	cfg = cfg.withProtocol(format.Protocol)
+	if !format.Framed {
+		encoder := cfg.NewEncoder(writer)
+		encoder.format = format
+		return encoder
+	}
+	return &Encoder{
+		cfg:    cfg,
+		stream: cfg.NewStream(nil, nil),
+		format: format,
+		writer: writer,
 	}
 }
 
+// withProtocol returns the config sharing extensions and options with cfg
+func (cfg *frozenConfig) withProtocol(protocol Protocol) *frozenConfig {
+	if protocol == cfg.protocol {
+		return cfg
+	}
+	sibling, found := cfg.siblings.Load(protocol)
+	if found {
+		return sibling.(*frozenConfig)
+	}
+	sibling, _ = cfg.siblings.LoadOrStore(protocol, &frozenConfig{
+		extension:      cfg.extension,
+		protocol:       protocol,
+		staticCodegen:  cfg.staticCodegen,
+		omitPolicy:     cfg.omitPolicy,
+		compactVersion: cfg.compactVersion,
+		zeroCopy:       cfg.zeroCopy,
+		zeroCopyString: cfg.zeroCopyString,
+		maxDepth:       cfg.maxDepth,
+		reuseValues:    cfg.reuseValues,
+		stringPolicy:   cfg.stringPolicy,
+	})
+	return sibling.(*frozenConfig)
+}
+
+// ToJSON decodes the message into general objects, binary not valid as utf8 is base64 encoded
+// unless the config decodes every binary as []byte
 func (cfg *frozenConfig) ToJSON(buf []byte) (string, error) {
-	msg, err := UnmarshalMessage(buf)
-	if err != nil {
-		return "", err
+	policy := cfg.stringPolicy
+	if policy == spi.StringAlways {
+		policy = spi.BinaryIfInvalidUTF8
+	}
+	iter := cfg.BorrowIterator(nil, buf)
+	defer cfg.ReturnIterator(iter)
+	msg := general.Message{MessageHeader: iter.ReadMessageHeader()}
+	if iter.Error() == nil {
+		msg.Arguments = general.ReadWithPolicy(iter, protocol.TypeStruct, policy).(general.Struct)
+	}
+	if iter.Error() != nil {
+		return "", iter.Error()
 	}
 	jsonEncoded, err := json.MarshalIndent(msg, "", "  ")
 	if err != nil {
@@ -1,6 +1,8 @@
 package thrifter
 
 import (
+	"bufio"
+	"bytes"
 	"github.com/batchcorp/thrift-iterator/general"
 	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/spi"
@@ -9,11 +11,19 @@ import (
 )
 
 type Decoder struct {
-	cfg  *frozenConfig
-	iter spi.Iterator
+	cfg    *frozenConfig
+	iter   spi.Iterator
+	format Format
+	// reader is set by NewAutoDecoder, iter is created after the format is detected
+	reader *bufio.Reader
 }
 
 func (decoder *Decoder) Decode(val interface{}) error {
+	if decoder.iter == nil {
+		if err := decoder.detect(); err != nil {
+			return err
+		}
+	}
 	cfg := decoder.cfg
 	valType := reflect.TypeOf(val)
 	valDecoder := cfg.getGenDecoder(valType)
@@ -28,6 +38,20 @@ func (decoder *Decoder) Decode(val interface{}) error {
 	return nil
 }
 
+// Skip reads the next value of ttype without decoding it, the encoded bytes are returned
+func (decoder *Decoder) Skip(ttype protocol.TType) ([]byte, error) {
+	if decoder.iter == nil {
+		if err := decoder.detect(); err != nil {
+			return nil, err
+		}
+	}
+	buf := decoder.iter.Skip(ttype, nil)
+	if decoder.iter.Error() != nil {
+		return nil, decoder.iter.Error()
+	}
+	return buf, nil
+}
+
 func (decoder *Decoder) DecodeMessage() (general.Message, error) {
 	var msg general.Message
 	err := decoder.Decode(&msg)
@@ -46,6 +70,44 @@ func (decoder *Decoder) DecodeMessageArguments() (general.Struct, error) {
 	return msgArgs, err
 }
 
+// Format is known after decoding the first value if the decoder is created by NewAutoDecoder
+func (decoder *Decoder) Format() Format {
+	return decoder.format
+}
+
 func (decoder *Decoder) Reset(reader io.Reader, buf []byte) {
-	decoder.iter.Reset(reader, buf)
+	if decoder.reader == nil {
+		decoder.iter.Reset(reader, buf)
+		return
+	}
+	if reader == nil {
+		reader = bytes.NewReader(buf)
+	} else if buf != nil {
+		reader = io.MultiReader(bytes.NewReader(buf), reader)
+	}
+	decoder.reader.Reset(reader)
+	decoder.iter = nil
+	decoder.format = Format{}
+}
+
+func (decoder *Decoder) detect() error {
+	format, err := detectFormat(decoder.reader)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader = decoder.reader
+	if format.Framed {
+		frames := NewFrameReader(decoder.reader, format.Header)
+		if format.Header {
+			if err := frames.next(); err != nil {
+				return err
+			}
+			format.Protocol = frames.protocol
+		}
+		reader = frames
+	}
+	decoder.format = format
+	decoder.cfg = decoder.cfg.withProtocol(format.Protocol)
+	decoder.iter = decoder.cfg.NewIterator(reader, nil)
+	return nil
 }
@@ -0,0 +1,64 @@
+package thrifter
+
+import (
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"sort"
+)
+
+// DiffRaw compares the structs field by field, only the fields of different bytes are decoded to find
+// the paths changed within. The buffers must be encoded in the protocol of cfg
+func (cfg *frozenConfig) DiffRaw(old raw.Struct, new raw.Struct) ([]general.Difference, error) {
+	added, removed, changed := raw.DiffFields(old, new)
+	var diffs []general.Difference
+	for _, fieldId := range changed {
+		oldVal, err := cfg.readRawField(old[fieldId])
+		if err != nil {
+			return nil, err
+		}
+		newVal, err := cfg.readRawField(new[fieldId])
+		if err != nil {
+			return nil, err
+		}
+		for _, diff := range general.Diff(oldVal, newVal) {
+			diff.Path = append([]interface{}{fieldId}, diff.Path...)
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, fieldId := range removed {
+		oldVal, err := cfg.readRawField(old[fieldId])
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, general.Difference{Kind: general.Removed, Path: []interface{}{fieldId},
+			OldType: old[fieldId].Type, Old: oldVal})
+	}
+	for _, fieldId := range added {
+		newVal, err := cfg.readRawField(new[fieldId])
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, general.Difference{Kind: general.Added, Path: []interface{}{fieldId},
+			NewType: new[fieldId].Type, New: newVal})
+	}
+	sortDiffs(diffs)
+	return diffs, nil
+}
+
+func (cfg *frozenConfig) readRawField(field raw.StructField) (interface{}, error) {
+	iter := cfg.BorrowIterator(nil, field.Buffer)
+	defer cfg.ReturnIterator(iter)
+	val := general.ReadWithPolicy(iter, field.Type, cfg.stringPolicy)
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+	return val, nil
+}
+
+// sortDiffs orders the differences by field id, keeping the order within same field
+func sortDiffs(diffs []general.Difference) {
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Path[0].(protocol.FieldId) < diffs[j].Path[0].(protocol.FieldId)
+	})
+}
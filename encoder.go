@@ -11,6 +11,10 @@ import (
 type Encoder struct {
 	cfg    *frozenConfig
 	stream spi.Stream
+	format Format
+	// writer is set when the messages are framed, the stream buffers the message until it is complete
+	writer io.Writer
+	seqId  protocol.SeqId
 }
 
 func (encoder *Encoder) Encode(val interface{}) error {
@@ -22,6 +26,9 @@ func (encoder *Encoder) Encode(val interface{}) error {
 		cfg.addGenEncoder(valType, valEncoder)
 	}
 	valEncoder.Encode(val, encoder.stream)
+	if encoder.format.Framed {
+		return encoder.writeFrame(val)
+	}
 	encoder.stream.Flush()
 	if encoder.stream.Error() != nil {
 		return encoder.stream.Error()
@@ -29,6 +36,35 @@ func (encoder *Encoder) Encode(val interface{}) error {
 	return nil
 }
 
+// writeFrame holds the message header until the arguments are encoded, so that the frame has the whole message
+func (encoder *Encoder) writeFrame(val interface{}) error {
+	stream := encoder.stream
+	if stream.Error() != nil {
+		err := stream.Error()
+		stream.Reset(nil)
+		return err
+	}
+	switch typedVal := val.(type) {
+	case protocol.MessageHeader:
+		encoder.seqId = typedVal.SeqId
+		return nil
+	case *protocol.MessageHeader:
+		encoder.seqId = typedVal.SeqId
+		return nil
+	case general.Message:
+		encoder.seqId = typedVal.SeqId
+	}
+	err := WriteFrame(encoder.writer, encoder.format, encoder.seqId, stream.Buffer())
+	stream.Reset(nil)
+	encoder.seqId = 0
+	return err
+}
+
+// Format is the format of messages written by the encoder
+func (encoder *Encoder) Format() Format {
+	return encoder.format
+}
+
 func (encoder *Encoder) EncodeMessage(msg general.Message) error {
 	return encoder.Encode(msg)
 }
@@ -42,6 +78,11 @@ func (encoder *Encoder) EncodeMessageArguments(msgArgs general.Struct) error {
 }
 
 func (encoder *Encoder) Reset(writer io.Writer) {
+	if encoder.format.Framed {
+		encoder.writer = writer
+		encoder.stream.Reset(nil)
+		return
+	}
 	encoder.stream.Reset(writer)
 }
 
@@ -0,0 +1,60 @@
+package stdtypes
+
+import (
+	"github.com/batchcorp/thrift-iterator/spi"
+	"math/big"
+	"net"
+	"net/url"
+)
+
+type bigIntDecoder struct {
+}
+
+func (decoder *bigIntDecoder) Decode(val interface{}, iter spi.Iterator) {
+	str := iter.ReadString()
+	if iter.Error() != nil {
+		return
+	}
+	if _, ok := val.(*big.Int).SetString(str, 10); !ok {
+		iter.ReportError("decode big.Int", "invalid number: "+str)
+	}
+}
+
+type ipDecoder struct {
+}
+
+func (decoder *ipDecoder) Decode(val interface{}, iter spi.Iterator) {
+	*val.(*net.IP) = net.IP(iter.ReadBinary())
+}
+
+type stringIPDecoder struct {
+}
+
+func (decoder *stringIPDecoder) Decode(val interface{}, iter spi.Iterator) {
+	str := iter.ReadString()
+	if iter.Error() != nil {
+		return
+	}
+	ip := net.ParseIP(str)
+	if ip == nil && str != "" {
+		iter.ReportError("decode net.IP", "invalid ip: "+str)
+		return
+	}
+	*val.(*net.IP) = ip
+}
+
+type urlDecoder struct {
+}
+
+func (decoder *urlDecoder) Decode(val interface{}, iter spi.Iterator) {
+	str := iter.ReadString()
+	if iter.Error() != nil {
+		return
+	}
+	u, err := url.Parse(str)
+	if err != nil {
+		iter.ReportError("decode url.URL", err.Error())
+		return
+	}
+	*val.(*url.URL) = *u
+}
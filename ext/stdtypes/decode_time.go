@@ -0,0 +1,51 @@
+package stdtypes
+
+import (
+	"github.com/batchcorp/thrift-iterator/spi"
+	"time"
+)
+
+type unixMillisTimeDecoder struct {
+}
+
+func (decoder *unixMillisTimeDecoder) Decode(val interface{}, iter spi.Iterator) {
+	millis := iter.ReadInt64()
+	*val.(*time.Time) = time.Unix(millis/1000, (millis%1000)*int64(time.Millisecond))
+}
+
+type unixNanosTimeDecoder struct {
+}
+
+func (decoder *unixNanosTimeDecoder) Decode(val interface{}, iter spi.Iterator) {
+	*val.(*time.Time) = time.Unix(0, iter.ReadInt64())
+}
+
+type rfc3339TimeDecoder struct {
+}
+
+func (decoder *rfc3339TimeDecoder) Decode(val interface{}, iter spi.Iterator) {
+	str := iter.ReadString()
+	if iter.Error() != nil {
+		return
+	}
+	t, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		iter.ReportError("decode time.Time", err.Error())
+		return
+	}
+	*val.(*time.Time) = t
+}
+
+type durationDecoder struct {
+}
+
+func (decoder *durationDecoder) Decode(val interface{}, iter spi.Iterator) {
+	*val.(*time.Duration) = time.Duration(iter.ReadInt64())
+}
+
+type millisDurationDecoder struct {
+}
+
+func (decoder *millisDurationDecoder) Decode(val interface{}, iter spi.Iterator) {
+	*val.(*time.Duration) = time.Duration(iter.ReadInt64()) * time.Millisecond
+}
@@ -0,0 +1,60 @@
+package stdtypes
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"math/big"
+	"net"
+	"net/url"
+)
+
+type bigIntEncoder struct {
+}
+
+func (encoder *bigIntEncoder) Encode(val interface{}, stream spi.Stream) {
+	bigInt := val.(big.Int)
+	stream.WriteString(bigInt.Text(10))
+}
+
+func (encoder *bigIntEncoder) ThriftType() protocol.TType {
+	return protocol.TypeString
+}
+
+type ipEncoder struct {
+}
+
+func (encoder *ipEncoder) Encode(val interface{}, stream spi.Stream) {
+	stream.WriteBinary(val.(net.IP))
+}
+
+func (encoder *ipEncoder) ThriftType() protocol.TType {
+	return protocol.TypeString
+}
+
+type stringIPEncoder struct {
+}
+
+func (encoder *stringIPEncoder) Encode(val interface{}, stream spi.Stream) {
+	ip := val.(net.IP)
+	if ip == nil {
+		stream.WriteString("")
+		return
+	}
+	stream.WriteString(ip.String())
+}
+
+func (encoder *stringIPEncoder) ThriftType() protocol.TType {
+	return protocol.TypeString
+}
+
+type urlEncoder struct {
+}
+
+func (encoder *urlEncoder) Encode(val interface{}, stream spi.Stream) {
+	u := val.(url.URL)
+	stream.WriteString(u.String())
+}
+
+func (encoder *urlEncoder) ThriftType() protocol.TType {
+	return protocol.TypeString
+}
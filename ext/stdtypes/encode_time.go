@@ -0,0 +1,64 @@
+package stdtypes
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"time"
+)
+
+type unixMillisTimeEncoder struct {
+}
+
+func (encoder *unixMillisTimeEncoder) Encode(val interface{}, stream spi.Stream) {
+	t := val.(time.Time)
+	// not using UnixNano, which overflows outside of year 1678 to 2262
+	stream.WriteInt64(t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond))
+}
+
+func (encoder *unixMillisTimeEncoder) ThriftType() protocol.TType {
+	return protocol.TypeI64
+}
+
+type unixNanosTimeEncoder struct {
+}
+
+func (encoder *unixNanosTimeEncoder) Encode(val interface{}, stream spi.Stream) {
+	stream.WriteInt64(val.(time.Time).UnixNano())
+}
+
+func (encoder *unixNanosTimeEncoder) ThriftType() protocol.TType {
+	return protocol.TypeI64
+}
+
+type rfc3339TimeEncoder struct {
+}
+
+func (encoder *rfc3339TimeEncoder) Encode(val interface{}, stream spi.Stream) {
+	stream.WriteString(val.(time.Time).Format(time.RFC3339Nano))
+}
+
+func (encoder *rfc3339TimeEncoder) ThriftType() protocol.TType {
+	return protocol.TypeString
+}
+
+type durationEncoder struct {
+}
+
+func (encoder *durationEncoder) Encode(val interface{}, stream spi.Stream) {
+	stream.WriteInt64(int64(val.(time.Duration)))
+}
+
+func (encoder *durationEncoder) ThriftType() protocol.TType {
+	return protocol.TypeI64
+}
+
+type millisDurationEncoder struct {
+}
+
+func (encoder *millisDurationEncoder) Encode(val interface{}, stream spi.Stream) {
+	stream.WriteInt64(int64(val.(time.Duration) / time.Millisecond))
+}
+
+func (encoder *millisDurationEncoder) ThriftType() protocol.TType {
+	return protocol.TypeI64
+}
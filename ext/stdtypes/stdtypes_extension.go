@@ -0,0 +1,96 @@
+// Package stdtypes encodes commonly used standard library types,
+// so that they can be used as struct fields without converting by hand.
+//
+//	time.Time      i64 unix millis, or "nanos" (i64 unix nanos), "rfc3339" (string)
+//	time.Duration  i64 nanoseconds, or "millis" (i64 milliseconds)
+//	big.Int        string in decimal
+//	net.IP         binary, or "string" (textual form)
+//	url.URL        string
+//
+// the options are given after field id in the struct tag, like `thrift:"created,1,rfc3339"`
+package stdtypes
+
+import (
+	"github.com/batchcorp/thrift-iterator/spi"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf((*time.Time)(nil)).Elem()
+var durationType = reflect.TypeOf((*time.Duration)(nil)).Elem()
+var bigIntType = reflect.TypeOf((*big.Int)(nil)).Elem()
+var ipType = reflect.TypeOf((*net.IP)(nil)).Elem()
+var urlType = reflect.TypeOf((*url.URL)(nil)).Elem()
+
+type Extension struct {
+}
+
+func (extension *Extension) DecoderOf(valType reflect.Type) spi.ValDecoder {
+	return extension.FieldDecoderOf(valType, nil)
+}
+
+func (extension *Extension) EncoderOf(valType reflect.Type) spi.ValEncoder {
+	return extension.FieldEncoderOf(valType, nil)
+}
+
+func (extension *Extension) FieldDecoderOf(valType reflect.Type, options []string) spi.ValDecoder {
+	if valType.Kind() != reflect.Ptr {
+		return nil
+	}
+	switch valType.Elem() {
+	case timeType:
+		switch {
+		case spi.HasOption(options, "rfc3339"):
+			return &rfc3339TimeDecoder{}
+		case spi.HasOption(options, "nanos"):
+			return &unixNanosTimeDecoder{}
+		}
+		return &unixMillisTimeDecoder{}
+	case durationType:
+		if spi.HasOption(options, "millis") {
+			return &millisDurationDecoder{}
+		}
+		return &durationDecoder{}
+	case bigIntType:
+		return &bigIntDecoder{}
+	case ipType:
+		if spi.HasOption(options, "string") {
+			return &stringIPDecoder{}
+		}
+		return &ipDecoder{}
+	case urlType:
+		return &urlDecoder{}
+	}
+	return nil
+}
+
+func (extension *Extension) FieldEncoderOf(valType reflect.Type, options []string) spi.ValEncoder {
+	switch valType {
+	case timeType:
+		switch {
+		case spi.HasOption(options, "rfc3339"):
+			return &rfc3339TimeEncoder{}
+		case spi.HasOption(options, "nanos"):
+			return &unixNanosTimeEncoder{}
+		}
+		return &unixMillisTimeEncoder{}
+	case durationType:
+		if spi.HasOption(options, "millis") {
+			return &millisDurationEncoder{}
+		}
+		return &durationEncoder{}
+	case bigIntType:
+		return &bigIntEncoder{}
+	case ipType:
+		if spi.HasOption(options, "string") {
+			return &stringIPEncoder{}
+		}
+		return &ipEncoder{}
+	case urlType:
+		return &urlEncoder{}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package thrifter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"io"
+)
+
+// Format is how the messages are put on the wire
+type Format struct {
+	Protocol Protocol
+	// Framed messages are prefixed by 4 bytes big endian length
+	Framed bool
+	// Header is the THeader transport, the frames start with a header before the message.
+	// Header implies Framed, only the frames without transforms are supported,
+	// and all the frames must use the protocol of the first frame
+	Header bool
+}
+
+// MaxFrameSize limits the size of frame accepted, same as apache thrift
+const MaxFrameSize = 16384000
+
+var ErrFrameTooLarge = errors.New("frame too large")
+
+const headerMagic = 0x0FFF
+
+// the protocol ids used in THeader
+const (
+	headerProtocolBinary  = 0
+	headerProtocolCompact = 2
+)
+
+var ErrUnknownFormat = errors.New("unknown thrift format")
+
+// detectFormat tells the format from the leading bytes, the protocol of THeader is known only after reading the header
+func detectFormat(reader *bufio.Reader) (Format, error) {
+	leading, err := reader.Peek(2)
+	if err != nil {
+		return Format{}, err
+	}
+	if format, detected := detectProtocol(leading); detected {
+		return format, nil
+	}
+	leading, err = reader.Peek(6)
+	if err != nil {
+		return Format{}, err
+	}
+	if binary.BigEndian.Uint16(leading[4:]) == headerMagic {
+		return Format{Framed: true, Header: true}, nil
+	}
+	if format, detected := detectProtocol(leading[4:]); detected {
+		format.Framed = true
+		return format, nil
+	}
+	return Format{}, fmt.Errorf("%w: leading bytes % x", ErrUnknownFormat, leading)
+}
+
+func detectProtocol(leading []byte) (Format, bool) {
+	if binary.BigEndian.Uint16(leading) == protocol.BINARY_VERSION_1>>16 {
+		return Format{Protocol: ProtocolBinary}, true
+	}
+	if leading[0] == protocol.COMPACT_PROTOCOL_ID {
+		return Format{Protocol: ProtocolCompact}, true
+	}
+	return Format{}, false
+}
+
+// FrameReader reads the frames of framed or THeader transport,
+// as io.Reader it joins the payloads of frames into one stream
+type FrameReader struct {
+	reader   io.Reader
+	header   bool
+	protocol Protocol
+	frameLen []byte
+	buf      []byte
+	payload  []byte
+}
+
+// NewFrameReader reads THeader frames if header is true, otherwise the frames prefixed by length only
+func NewFrameReader(reader io.Reader, header bool) *FrameReader {
+	return &FrameReader{reader: reader, header: header, frameLen: make([]byte, 4)}
+}
+
+// Next returns the payload of next frame, which is valid until the next read
+func (frames *FrameReader) Next() ([]byte, error) {
+	if err := frames.next(); err != nil {
+		return nil, err
+	}
+	payload := frames.payload
+	frames.payload = nil
+	return payload, nil
+}
+
+// Protocol is the protocol of THeader frames, known after the first frame is read
+func (frames *FrameReader) Protocol() Protocol {
+	return frames.protocol
+}
+
+func (frames *FrameReader) Read(p []byte) (int, error) {
+	for len(frames.payload) == 0 {
+		if err := frames.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, frames.payload)
+	frames.payload = frames.payload[n:]
+	return n, nil
+}
+
+func (frames *FrameReader) next() error {
+	if _, err := io.ReadFull(frames.reader, frames.frameLen); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(frames.frameLen)
+	if size > MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+	if cap(frames.buf) < int(size) {
+		frames.buf = make([]byte, size)
+	}
+	frame := frames.buf[:size]
+	if _, err := io.ReadFull(frames.reader, frame); err != nil {
+		return err
+	}
+	if !frames.header {
+		frames.payload = frame
+		return nil
+	}
+	payload, protocolId, err := parseHeader(frame)
+	if err != nil {
+		return err
+	}
+	var frameProtocol Protocol
+	switch protocolId {
+	case headerProtocolBinary:
+		frameProtocol = ProtocolBinary
+	case headerProtocolCompact:
+		frameProtocol = ProtocolCompact
+	default:
+		return fmt.Errorf("unsupported THeader protocol id: %d", protocolId)
+	}
+	if frames.protocol != 0 && frames.protocol != frameProtocol {
+		return fmt.Errorf("THeader protocol id changed to %d", protocolId)
+	}
+	frames.protocol = frameProtocol
+	frames.payload = payload
+	return nil
+}
+
+// parseHeader skips magic, flags, sequence id and the info headers
+func parseHeader(frame []byte) ([]byte, uint64, error) {
+	if len(frame) < 10 || binary.BigEndian.Uint16(frame) != headerMagic {
+		return nil, 0, errors.New("invalid THeader frame")
+	}
+	headerSize := int(binary.BigEndian.Uint16(frame[8:])) * 4
+	if len(frame) < 10+headerSize {
+		return nil, 0, errors.New("THeader size exceeds frame")
+	}
+	header := frame[10 : 10+headerSize]
+	protocolId, n := binary.Uvarint(header)
+	if n <= 0 {
+		return nil, 0, errors.New("invalid THeader protocol id")
+	}
+	transforms, m := binary.Uvarint(header[n:])
+	if m <= 0 {
+		return nil, 0, errors.New("invalid THeader transforms")
+	}
+	if transforms != 0 {
+		return nil, 0, errors.New("THeader transforms are not supported")
+	}
+	return frame[10+headerSize:], protocolId, nil
+}
+
+// WriteFrame writes the message in buf as one frame of format, the seqId is used by THeader only
+func WriteFrame(writer io.Writer, format Format, seqId protocol.SeqId, buf []byte) error {
+	var prefix []byte
+	if format.Header {
+		protocolId := byte(headerProtocolBinary)
+		if format.Protocol == ProtocolCompact {
+			protocolId = headerProtocolCompact
+		}
+		prefix = make([]byte, 18)
+		binary.BigEndian.PutUint32(prefix, uint32(14+len(buf)))
+		binary.BigEndian.PutUint16(prefix[4:], headerMagic)
+		binary.BigEndian.PutUint32(prefix[8:], uint32(seqId))
+		// one word of header: protocol id, no transforms and padding
+		binary.BigEndian.PutUint16(prefix[12:], 1)
+		prefix[14] = protocolId
+	} else {
+		prefix = make([]byte, 4)
+		binary.BigEndian.PutUint32(prefix, uint32(len(buf)))
+	}
+	if _, err := writer.Write(append(prefix, buf...)); err != nil {
+		return err
+	}
+	if flusher, ok := writer.(protocol.Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
@@ -2,12 +2,15 @@ package general
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/spi"
 )
 
-func generalReaderOf(ttype protocol.TType) func(iter spi.Iterator) interface{} {
+type generalReader func(iter spi.Iterator, policy spi.StringPolicy) interface{}
+
+func generalReaderOf(ttype protocol.TType) generalReader {
 	switch ttype {
 	case protocol.TypeBool:
 		return readBool
@@ -36,30 +39,50 @@ func generalReaderOf(ttype protocol.TType) func(iter spi.Iterator) interface{} {
 	}
 }
 
-func readFloat64(iter spi.Iterator) interface{} {
+func readFloat64(iter spi.Iterator, policy spi.StringPolicy) interface{} {
 	return iter.ReadFloat64()
 }
 
-func readBool(iter spi.Iterator) interface{} {
+func readBool(iter spi.Iterator, policy spi.StringPolicy) interface{} {
 	return iter.ReadBool()
 }
 
-func readInt8(iter spi.Iterator) interface{} {
+func readInt8(iter spi.Iterator, policy spi.StringPolicy) interface{} {
 	return iter.ReadInt8()
 }
 
-func readInt16(iter spi.Iterator) interface{} {
+func readInt16(iter spi.Iterator, policy spi.StringPolicy) interface{} {
 	return iter.ReadInt16()
 }
 
-func readInt32(iter spi.Iterator) interface{} {
+func readInt32(iter spi.Iterator, policy spi.StringPolicy) interface{} {
 	return iter.ReadInt32()
 }
 
-func readInt64(iter spi.Iterator) interface{} {
+func readInt64(iter spi.Iterator, policy spi.StringPolicy) interface{} {
 	return iter.ReadInt64()
 }
 
-func readString(iter spi.Iterator) interface{} {
+func readString(iter spi.Iterator, policy spi.StringPolicy) interface{} {
+	switch policy {
+	case spi.BinaryAlways:
+		return iter.ReadBinary()
+	case spi.BinaryIfInvalidUTF8:
+		buf := iter.ReadBinary()
+		if utf8.Valid(buf) {
+			return string(buf)
+		}
+		return buf
+	}
 	return iter.ReadString()
 }
+
+// Read decodes a value of ttype into general objects, like List, Map and Struct
+func Read(iter spi.Iterator, ttype protocol.TType) interface{} {
+	return generalReaderOf(ttype)(iter, spi.StringAlways)
+}
+
+// ReadWithPolicy is Read decoding TypeString by the policy, map keys are always decoded as string
+func ReadWithPolicy(iter spi.Iterator, ttype protocol.TType, policy spi.StringPolicy) interface{} {
+	return generalReaderOf(ttype)(iter, policy)
+}
@@ -0,0 +1,27 @@
+package general
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+)
+
+type applicationExceptionDecoder struct {
+}
+
+func (decoder *applicationExceptionDecoder) Decode(val interface{}, iter spi.Iterator) {
+	exception := val.(*protocol.ApplicationException)
+	iter.ReadStructHeader()
+	for {
+		fieldType, fieldId := iter.ReadStructField()
+		switch {
+		case fieldType == protocol.TypeStop:
+			return
+		case fieldId == 1 && fieldType == protocol.TypeString:
+			exception.Message = iter.ReadString()
+		case fieldId == 2 && fieldType == protocol.TypeI32:
+			exception.Type = protocol.ApplicationExceptionType(iter.ReadInt32())
+		default:
+			iter.Discard(fieldType)
+		}
+	}
+}
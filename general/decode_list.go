@@ -3,18 +3,24 @@ package general
 import "github.com/batchcorp/thrift-iterator/spi"
 
 type generalListDecoder struct {
+	policy spi.StringPolicy
 }
 
 func (decoder *generalListDecoder) Decode(val interface{}, iter spi.Iterator) {
-	*val.(*List) = readList(iter).(List)
+	*val.(*List) = readList(iter, decoder.policy).(List)
 }
 
-func readList(iter spi.Iterator) interface{} {
+func readList(iter spi.Iterator, policy spi.StringPolicy) interface{} {
+	spi.EnterContainer(iter)
+	defer spi.LeaveContainer(iter)
 	elemType, length := iter.ReadListHeader()
+	generalList := List{ElementType: elemType}
+	if length == 0 {
+		return generalList
+	}
 	generalReader := generalReaderOf(elemType)
-	var generalList List
 	for i := 0; i < length; i++ {
-		generalList = append(generalList, generalReader(iter))
+		generalList.Elements = append(generalList.Elements, generalReader(iter, policy))
 	}
 	return generalList
 }
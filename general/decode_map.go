@@ -1,26 +1,77 @@
 package general
 
-import "github.com/batchcorp/thrift-iterator/spi"
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+)
 
 type generalMapDecoder struct {
+	policy spi.StringPolicy
 }
 
 func (decoder *generalMapDecoder) Decode(val interface{}, iter spi.Iterator) {
-	*val.(*Map) = readMap(iter).(Map)
+	generalMap, isMap := readMap(iter, decoder.policy).(Map)
+	if !isMap {
+		iter.ReportError("decode general.Map", "map keys are not hashable, decode into general.OrderedMap instead")
+		return
+	}
+	*val.(*Map) = generalMap
+}
+
+type generalOrderedMapDecoder struct {
+	policy spi.StringPolicy
+}
+
+func (decoder *generalOrderedMapDecoder) Decode(val interface{}, iter spi.Iterator) {
+	spi.EnterContainer(iter)
+	defer spi.LeaveContainer(iter)
+	keyType, elemType, length := iter.ReadMapHeader()
+	*val.(*OrderedMap) = readEntries(iter, keyType, elemType, length, decoder.policy)
 }
 
-func readMap(iter spi.Iterator) interface{} {
+// readMap reads OrderedMap if the keys are list, set, map or struct, otherwise Map.
+// The keys of Map are always string, as []byte is not hashable
+func readMap(iter spi.Iterator, policy spi.StringPolicy) interface{} {
+	spi.EnterContainer(iter)
+	defer spi.LeaveContainer(iter)
 	keyType, elemType, length := iter.ReadMapHeader()
-	generalMap := Map{}
+	if !isHashable(keyType) {
+		return readEntries(iter, keyType, elemType, length, policy)
+	}
+	generalMap := Map{KeyType: keyType, ElementType: elemType, Entries: map[interface{}]interface{}{}}
 	if length == 0 {
 		return generalMap
 	}
 	keyReader := generalReaderOf(keyType)
 	elemReader := generalReaderOf(elemType)
 	for i := 0; i < length; i++ {
-		key := keyReader(iter)
-		elem := elemReader(iter)
-		generalMap[key] = elem
+		key := keyReader(iter, spi.StringAlways)
+		elem := elemReader(iter, policy)
+		generalMap.Entries[key] = elem
 	}
 	return generalMap
-}
\ No newline at end of file
+}
+
+func readEntries(iter spi.Iterator, keyType protocol.TType, elemType protocol.TType, length int,
+	policy spi.StringPolicy) OrderedMap {
+	orderedMap := OrderedMap{KeyType: keyType, ElementType: elemType}
+	if length == 0 {
+		return orderedMap
+	}
+	keyReader := generalReaderOf(keyType)
+	elemReader := generalReaderOf(elemType)
+	for i := 0; i < length; i++ {
+		key := keyReader(iter, policy)
+		elem := elemReader(iter, policy)
+		orderedMap.Entries = append(orderedMap.Entries, MapEntry{Key: key, Element: elem})
+	}
+	return orderedMap
+}
+
+func isHashable(keyType protocol.TType) bool {
+	switch keyType {
+	case protocol.TypeList, protocol.TypeSet, protocol.TypeMap, protocol.TypeStruct:
+		return false
+	}
+	return true
+}
@@ -6,12 +6,13 @@ import (
 )
 
 type messageDecoder struct {
+	policy spi.StringPolicy
 }
 
 func (decoder *messageDecoder) Decode(val interface{}, iter spi.Iterator) {
 	*val.(*Message) = Message{
 		MessageHeader: iter.ReadMessageHeader(),
-		Arguments:     readStruct(iter).(Struct),
+		Arguments:     readStruct(iter, decoder.policy).(Struct),
 	}
 }
 
@@ -6,13 +6,14 @@ import (
 )
 
 type generalStructDecoder struct {
+	policy spi.StringPolicy
 }
 
 func (decoder *generalStructDecoder) Decode(val interface{}, iter spi.Iterator) {
-	*val.(*Struct) = readStruct(iter).(Struct)
+	*val.(*Struct) = readStruct(iter, decoder.policy).(Struct)
 }
 
-func readStruct(iter spi.Iterator) interface{} {
+func readStruct(iter spi.Iterator, policy spi.StringPolicy) interface{} {
 	generalStruct := Struct{}
 	iter.ReadStructHeader()
 	for {
@@ -21,6 +22,6 @@ func readStruct(iter spi.Iterator) interface{} {
 			return generalStruct
 		}
 		generalReader := generalReaderOf(fieldType)
-		generalStruct[fieldId] = generalReader(iter)
+		generalStruct[fieldId] = generalReader(iter, policy)
 	}
 }
\ No newline at end of file
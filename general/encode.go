@@ -1,12 +1,23 @@
 package general
 
 import (
+	"fmt"
 	"github.com/batchcorp/thrift-iterator/spi"
 	"github.com/batchcorp/thrift-iterator/protocol"
 	"reflect"
 )
 
+// generalWriterOf panics if sample is not general value
 func generalWriterOf(sample interface{}) (protocol.TType, func(val interface{}, stream spi.Stream)) {
+	ttype, generalWriter := lookupGeneralWriter(sample)
+	if generalWriter == nil {
+		panic("unsupported type: " + typeNameOf(sample))
+	}
+	return ttype, generalWriter
+}
+
+// lookupGeneralWriter returns nil writer if sample is nil or not general value
+func lookupGeneralWriter(sample interface{}) (protocol.TType, func(val interface{}, stream spi.Stream)) {
 	switch sample.(type) {
 	case bool:
 		return protocol.TypeBool, writeBool
@@ -36,11 +47,20 @@ func generalWriterOf(sample interface{}) (protocol.TType, func(val interface{},
 		return protocol.TypeList, writeList
 	case Map:
 		return protocol.TypeMap, writeMap
+	case OrderedMap:
+		return protocol.TypeMap, writeOrderedMap
 	case Struct:
 		return protocol.TypeStruct, writeStruct
 	default:
-		panic("unsupported type: " + reflect.TypeOf(sample).String())
+		return protocol.TypeStop, nil
+	}
+}
+
+func typeNameOf(val interface{}) string {
+	if val == nil {
+		return "nil"
 	}
+	return reflect.TypeOf(val).String()
 }
 
 func writeBool(val interface{}, stream spi.Stream) {
@@ -89,4 +109,20 @@ func writeString(val interface{}, stream spi.Stream) {
 
 func writeBinary(val interface{}, stream spi.Stream) {
 	stream.WriteBinary(val.([]byte))
-}
\ No newline at end of file
+}
+
+// writeTyped writes val if it is of the ttype, otherwise reports error instead of writing corrupted container
+func writeTyped(ttype protocol.TType, val interface{}, stream spi.Stream, operation string) bool {
+	valType, generalWriter := lookupGeneralWriter(val)
+	if generalWriter == nil {
+		stream.ReportError(operation, "unsupported element of type "+typeNameOf(val))
+		return false
+	}
+	// set is written same as list
+	if valType != ttype && !(valType == protocol.TypeList && ttype == protocol.TypeSet) {
+		stream.ReportError(operation, fmt.Sprintf("expected element of type %v but got %v", ttype, valType))
+		return false
+	}
+	generalWriter(val, stream)
+	return true
+}
@@ -0,0 +1,29 @@
+package general
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+)
+
+type applicationExceptionEncoder struct {
+}
+
+func (encoder *applicationExceptionEncoder) Encode(val interface{}, stream spi.Stream) {
+	exception := val.(protocol.ApplicationException)
+	stream.WriteStructHeader()
+	stream.WriteStructField(protocol.TypeString, 1)
+	stream.WriteString(exception.Message)
+	stream.WriteStructField(protocol.TypeI32, 2)
+	stream.WriteInt32(int32(exception.Type))
+	stream.WriteStructFieldStop()
+}
+
+func (encoder *applicationExceptionEncoder) ThriftType() protocol.TType {
+	return protocol.TypeStruct
+}
+
+// WriteException writes the exception reply of request
+func WriteException(stream spi.Stream, request protocol.MessageHeader, exception *protocol.ApplicationException) {
+	stream.WriteMessageHeader(protocol.ExceptionHeader(request))
+	(&applicationExceptionEncoder{}).Encode(*exception, stream)
+}
@@ -18,14 +18,19 @@ func (encoder *generalListEncoder) ThriftType() protocol.TType {
 
 func writeList(val interface{}, stream spi.Stream) {
 	obj := val.(List)
-	length := len(obj)
-	if length == 0 {
-		stream.WriteListHeader(protocol.TypeI64, 0)
-		return
+	length := len(obj.Elements)
+	elemType := obj.ElementType
+	if elemType == protocol.TypeStop {
+		if length == 0 {
+			stream.WriteListHeader(protocol.TypeI64, 0)
+			return
+		}
+		elemType, _ = lookupGeneralWriter(obj.Elements[0])
 	}
-	elemType, generalWriter := generalWriterOf(obj[0])
 	stream.WriteListHeader(elemType, length)
-	for _, elem := range obj {
-		generalWriter(elem, stream)
+	for _, elem := range obj.Elements {
+		if !writeTyped(elemType, elem, stream, "encode general.List") {
+			return
+		}
 	}
-}
\ No newline at end of file
+}
@@ -16,26 +16,63 @@ func (encoder *generalMapEncoder) ThriftType() protocol.TType {
 	return protocol.TypeMap
 }
 
-func takeSampleFromMap(sample Map) (interface{}, interface{}) {
-	for key, elem := range sample {
-		return key, elem
+type generalOrderedMapEncoder struct {
+}
+
+func (encoder *generalOrderedMapEncoder) Encode(val interface{}, stream spi.Stream) {
+	writeOrderedMap(val, stream)
+}
+
+func (encoder *generalOrderedMapEncoder) ThriftType() protocol.TType {
+	return protocol.TypeMap
+}
+
+// mapTypesOf fills in the key and element types not set from the sample entry
+func mapTypesOf(keyType protocol.TType, elemType protocol.TType,
+	sampleKey interface{}, sampleElem interface{}, hasSample bool) (protocol.TType, protocol.TType) {
+	if keyType == protocol.TypeStop {
+		keyType = protocol.TypeI64
+		if hasSample {
+			keyType, _ = lookupGeneralWriter(sampleKey)
+		}
 	}
-	panic("should not reach here")
+	if elemType == protocol.TypeStop {
+		elemType = protocol.TypeI64
+		if hasSample {
+			elemType, _ = lookupGeneralWriter(sampleElem)
+		}
+	}
+	return keyType, elemType
 }
 
 func writeMap(val interface{}, stream spi.Stream) {
 	obj := val.(Map)
-	length := len(obj)
-	if length == 0 {
-		stream.WriteMapHeader(protocol.TypeI64, protocol.TypeI64, 0)
-		return
+	var sampleKey, sampleElem interface{}
+	for sampleKey, sampleElem = range obj.Entries {
+		break
+	}
+	keyType, elemType := mapTypesOf(obj.KeyType, obj.ElementType, sampleKey, sampleElem, len(obj.Entries) > 0)
+	stream.WriteMapHeader(keyType, elemType, len(obj.Entries))
+	for key, elem := range obj.Entries {
+		if !writeTyped(keyType, key, stream, "encode general.Map") ||
+			!writeTyped(elemType, elem, stream, "encode general.Map") {
+			return
+		}
+	}
+}
+
+func writeOrderedMap(val interface{}, stream spi.Stream) {
+	obj := val.(OrderedMap)
+	var sample MapEntry
+	if len(obj.Entries) > 0 {
+		sample = obj.Entries[0]
 	}
-	keySample, elemSample := takeSampleFromMap(obj)
-	keyType, generalKeyWriter := generalWriterOf(keySample)
-	elemType, generalElemWriter := generalWriterOf(elemSample)
-	stream.WriteMapHeader(keyType, elemType, length)
-	for key, elem := range obj {
-		generalKeyWriter(key, stream)
-		generalElemWriter(elem, stream)
+	keyType, elemType := mapTypesOf(obj.KeyType, obj.ElementType, sample.Key, sample.Element, len(obj.Entries) > 0)
+	stream.WriteMapHeader(keyType, elemType, len(obj.Entries))
+	for _, entry := range obj.Entries {
+		if !writeTyped(keyType, entry.Key, stream, "encode general.OrderedMap") ||
+			!writeTyped(elemType, entry.Element, stream, "encode general.OrderedMap") {
+			return
+		}
 	}
-}
\ No newline at end of file
+}
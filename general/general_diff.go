@@ -0,0 +1,373 @@
+package general
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"sort"
+	"strings"
+)
+
+type DiffKind int
+
+const (
+	// Added is the path only in the new value
+	Added DiffKind = iota + 1
+	// Removed is the path only in the old value
+	Removed
+	// Changed is the path in both values, with different values or types
+	Changed
+)
+
+func (kind DiffKind) String() string {
+	switch kind {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(kind))
+}
+
+// Difference is one path differing between two values, OldType is TypeStop if added, NewType is TypeStop if removed
+type Difference struct {
+	Kind    DiffKind
+	Path    []interface{}
+	OldType protocol.TType
+	NewType protocol.TType
+	Old     interface{}
+	New     interface{}
+}
+
+func (diff Difference) String() string {
+	switch diff.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s %v %v", FormatPath(diff.Path), diff.NewType, diff.New)
+	case Removed:
+		return fmt.Sprintf("- %s %v %v", FormatPath(diff.Path), diff.OldType, diff.Old)
+	}
+	return fmt.Sprintf("~ %s %v %v -> %v %v", FormatPath(diff.Path), diff.OldType, diff.Old, diff.NewType, diff.New)
+}
+
+// FormatPath is the reverse of ParsePath, list index is formatted as [n]
+func FormatPath(path []interface{}) string {
+	segments := make([]string, len(path))
+	for i, key := range path {
+		if index, isIndex := key.(int); isIndex {
+			segments[i] = fmt.Sprintf("[%d]", index)
+		} else {
+			segments[i] = fmt.Sprint(key)
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// Equal tells if the general values encode to same thrift values.
+// Map entries are compared regardless of order, string and []byte of same bytes are equal
+func Equal(a interface{}, b interface{}) bool {
+	switch typedA := a.(type) {
+	case Struct:
+		typedB, ok := b.(Struct)
+		if !ok || len(typedA) != len(typedB) {
+			return false
+		}
+		for fieldId, fieldA := range typedA {
+			fieldB, found := typedB[fieldId]
+			if !found || !Equal(fieldA, fieldB) {
+				return false
+			}
+		}
+		return true
+	case List:
+		typedB, ok := b.(List)
+		if !ok || len(typedA.Elements) != len(typedB.Elements) || listTypeOf(typedA) != listTypeOf(typedB) {
+			return false
+		}
+		for i, elemA := range typedA.Elements {
+			if !Equal(elemA, typedB.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case Map:
+		typedB, ok := b.(Map)
+		if !ok || len(typedA.Entries) != len(typedB.Entries) || !sameMapTypes(typedA, typedB) {
+			return false
+		}
+		for key, elemA := range typedA.Entries {
+			elemB, found := typedB.Entries[key]
+			if !found || !Equal(elemA, elemB) {
+				return false
+			}
+		}
+		return true
+	case OrderedMap:
+		typedB, ok := b.(OrderedMap)
+		if !ok || len(typedA.Entries) != len(typedB.Entries) || !sameMapTypes(typedA, typedB) {
+			return false
+		}
+		for _, entry := range typedA.Entries {
+			elemB, found := findEntry(typedB.Entries, entry.Key)
+			if !found || !Equal(entry.Element, elemB) {
+				return false
+			}
+		}
+		return true
+	case Message:
+		typedB, ok := b.(Message)
+		return ok && typedA.MessageHeader == typedB.MessageHeader && Equal(typedA.Arguments, typedB.Arguments)
+	case []byte:
+		switch typedB := b.(type) {
+		case []byte:
+			return bytes.Equal(typedA, typedB)
+		case string:
+			return string(typedA) == typedB
+		}
+		return false
+	case string:
+		if typedB, isBytes := b.([]byte); isBytes {
+			return typedA == string(typedB)
+		}
+	}
+	return a == b
+}
+
+// Clone deep copies the general value, so that modifying the copy does not change the original
+func Clone(val interface{}) interface{} {
+	switch typed := val.(type) {
+	case Struct:
+		if typed == nil {
+			return typed
+		}
+		cloned := make(Struct, len(typed))
+		for fieldId, field := range typed {
+			cloned[fieldId] = Clone(field)
+		}
+		return cloned
+	case List:
+		if typed.Elements == nil {
+			return typed
+		}
+		elements := make([]interface{}, len(typed.Elements))
+		for i, elem := range typed.Elements {
+			elements[i] = Clone(elem)
+		}
+		return List{ElementType: typed.ElementType, Elements: elements}
+	case Map:
+		if typed.Entries == nil {
+			return typed
+		}
+		entries := make(map[interface{}]interface{}, len(typed.Entries))
+		for key, elem := range typed.Entries {
+			entries[key] = Clone(elem)
+		}
+		return Map{KeyType: typed.KeyType, ElementType: typed.ElementType, Entries: entries}
+	case OrderedMap:
+		if typed.Entries == nil {
+			return typed
+		}
+		entries := make([]MapEntry, len(typed.Entries))
+		for i, entry := range typed.Entries {
+			entries[i] = MapEntry{Key: Clone(entry.Key), Element: Clone(entry.Element)}
+		}
+		return OrderedMap{KeyType: typed.KeyType, ElementType: typed.ElementType, Entries: entries}
+	case Message:
+		arguments, _ := Clone(typed.Arguments).(Struct)
+		return Message{MessageHeader: typed.MessageHeader, Arguments: arguments}
+	case []byte:
+		if typed == nil {
+			return typed
+		}
+		return append([]byte{}, typed...)
+	}
+	return val
+}
+
+// Diff lists the paths differing from old to new, containers of different element types are changed as whole.
+// Struct fields and map keys are in ascending order
+func Diff(old interface{}, new interface{}) []Difference {
+	var diffs []Difference
+	return diffInto(diffs, nil, old, new)
+}
+
+func diffInto(diffs []Difference, path []interface{}, old interface{}, new interface{}) []Difference {
+	switch typedOld := old.(type) {
+	case Struct:
+		typedNew, ok := new.(Struct)
+		if !ok {
+			break
+		}
+		fieldIds := make([]protocol.FieldId, 0, len(typedOld)+len(typedNew))
+		for fieldId := range typedOld {
+			fieldIds = append(fieldIds, fieldId)
+		}
+		for fieldId := range typedNew {
+			if _, found := typedOld[fieldId]; !found {
+				fieldIds = append(fieldIds, fieldId)
+			}
+		}
+		sort.Slice(fieldIds, func(i, j int) bool {
+			return fieldIds[i] < fieldIds[j]
+		})
+		for _, fieldId := range fieldIds {
+			oldField, inOld := typedOld[fieldId]
+			newField, inNew := typedNew[fieldId]
+			diffs = diffChild(diffs, path, fieldId, oldField, inOld, newField, inNew)
+		}
+		return diffs
+	case List:
+		typedNew, ok := new.(List)
+		if !ok || listTypeOf(typedOld) != listTypeOf(typedNew) {
+			break
+		}
+		length := len(typedOld.Elements)
+		if len(typedNew.Elements) > length {
+			length = len(typedNew.Elements)
+		}
+		for i := 0; i < length; i++ {
+			var oldElem, newElem interface{}
+			inOld, inNew := i < len(typedOld.Elements), i < len(typedNew.Elements)
+			if inOld {
+				oldElem = typedOld.Elements[i]
+			}
+			if inNew {
+				newElem = typedNew.Elements[i]
+			}
+			diffs = diffChild(diffs, path, i, oldElem, inOld, newElem, inNew)
+		}
+		return diffs
+	case Map:
+		typedNew, ok := new.(Map)
+		if !ok || !sameMapTypes(typedOld, typedNew) {
+			break
+		}
+		keys := make([]interface{}, 0, len(typedOld.Entries)+len(typedNew.Entries))
+		for key := range typedOld.Entries {
+			keys = append(keys, key)
+		}
+		for key := range typedNew.Entries {
+			if _, found := typedOld.Entries[key]; !found {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keyLess(keys[i], keys[j])
+		})
+		for _, key := range keys {
+			oldElem, inOld := typedOld.Entries[key]
+			newElem, inNew := typedNew.Entries[key]
+			diffs = diffChild(diffs, path, key, oldElem, inOld, newElem, inNew)
+		}
+		return diffs
+	case OrderedMap:
+		typedNew, ok := new.(OrderedMap)
+		if !ok || !sameMapTypes(typedOld, typedNew) {
+			break
+		}
+		// keys not hashable are kept in the order read, old keys first
+		for _, entry := range typedOld.Entries {
+			newElem, inNew := findEntry(typedNew.Entries, entry.Key)
+			diffs = diffChild(diffs, path, entry.Key, entry.Element, true, newElem, inNew)
+		}
+		for _, entry := range typedNew.Entries {
+			if _, inOld := findEntry(typedOld.Entries, entry.Key); !inOld {
+				diffs = diffChild(diffs, path, entry.Key, nil, false, entry.Element, true)
+			}
+		}
+		return diffs
+	}
+	if Equal(old, new) {
+		return diffs
+	}
+	return append(diffs, Difference{Kind: Changed, Path: path,
+		OldType: thriftTypeOf(old), NewType: thriftTypeOf(new), Old: old, New: new})
+}
+
+func diffChild(diffs []Difference, path []interface{}, key interface{},
+	old interface{}, inOld bool, new interface{}, inNew bool) []Difference {
+	childPath := make([]interface{}, len(path), len(path)+1)
+	copy(childPath, path)
+	childPath = append(childPath, key)
+	switch {
+	case !inOld:
+		return append(diffs, Difference{Kind: Added, Path: childPath, NewType: thriftTypeOf(new), New: new})
+	case !inNew:
+		return append(diffs, Difference{Kind: Removed, Path: childPath, OldType: thriftTypeOf(old), Old: old})
+	}
+	return diffInto(diffs, childPath, old, new)
+}
+
+// thriftTypeOf is the type the general value is encoded as, TypeStop if not general value
+func thriftTypeOf(val interface{}) protocol.TType {
+	ttype, _ := lookupGeneralWriter(val)
+	return ttype
+}
+
+// listTypeOf is the element type the list is encoded with
+func listTypeOf(obj List) protocol.TType {
+	if obj.ElementType != protocol.TypeStop {
+		return obj.ElementType
+	}
+	if len(obj.Elements) == 0 {
+		return protocol.TypeI64
+	}
+	return thriftTypeOf(obj.Elements[0])
+}
+
+// sameMapTypes tells if both maps are encoded with same key and element types
+func sameMapTypes(a interface{}, b interface{}) bool {
+	keyA, elemA := mapTypesOfValue(a)
+	keyB, elemB := mapTypesOfValue(b)
+	return keyA == keyB && elemA == elemB
+}
+
+func mapTypesOfValue(val interface{}) (protocol.TType, protocol.TType) {
+	switch obj := val.(type) {
+	case Map:
+		var sampleKey, sampleElem interface{}
+		for sampleKey, sampleElem = range obj.Entries {
+			break
+		}
+		return mapTypesOf(obj.KeyType, obj.ElementType, sampleKey, sampleElem, len(obj.Entries) > 0)
+	case OrderedMap:
+		var sample MapEntry
+		if len(obj.Entries) > 0 {
+			sample = obj.Entries[0]
+		}
+		return mapTypesOf(obj.KeyType, obj.ElementType, sample.Key, sample.Element, len(obj.Entries) > 0)
+	}
+	return protocol.TypeStop, protocol.TypeStop
+}
+
+// keyLess orders the map keys of same type, numbers by value and others by formatted string
+func keyLess(a interface{}, b interface{}) bool {
+	switch typedA := a.(type) {
+	case int8:
+		if typedB, isSame := b.(int8); isSame {
+			return typedA < typedB
+		}
+	case int16:
+		if typedB, isSame := b.(int16); isSame {
+			return typedA < typedB
+		}
+	case int32:
+		if typedB, isSame := b.(int32); isSame {
+			return typedA < typedB
+		}
+	case int64:
+		if typedB, isSame := b.(int64); isSame {
+			return typedA < typedB
+		}
+	case float64:
+		if typedB, isSame := b.(float64); isSame {
+			return typedA < typedB
+		}
+	case string:
+		if typedB, isSame := b.(string); isSame {
+			return typedA < typedB
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
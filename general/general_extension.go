@@ -7,20 +7,26 @@ import (
 )
 
 type Extension struct {
+	// StringPolicy decides how the general objects decode TypeString
+	StringPolicy spi.StringPolicy
 }
 
 func (ext *Extension) EncoderOf(valType reflect.Type) spi.ValEncoder {
 	switch valType {
-	case reflect.TypeOf(List(nil)):
+	case reflect.TypeOf((*List)(nil)).Elem():
 		return &generalListEncoder{}
-	case reflect.TypeOf(Map(nil)):
+	case reflect.TypeOf((*Map)(nil)).Elem():
 		return &generalMapEncoder{}
+	case reflect.TypeOf((*OrderedMap)(nil)).Elem():
+		return &generalOrderedMapEncoder{}
 	case reflect.TypeOf(Struct(nil)):
 		return &generalStructEncoder{}
 	case reflect.TypeOf((*Message)(nil)).Elem():
 		return &messageEncoder{}
 	case reflect.TypeOf((*protocol.MessageHeader)(nil)).Elem():
 		return &messageHeaderEncoder{}
+	case reflect.TypeOf((*protocol.ApplicationException)(nil)).Elem():
+		return &applicationExceptionEncoder{}
 	}
 	return nil
 }
@@ -28,15 +34,19 @@ func (ext *Extension) EncoderOf(valType reflect.Type) spi.ValEncoder {
 func (ext *Extension) DecoderOf(valType reflect.Type) spi.ValDecoder {
 	switch valType {
 	case reflect.TypeOf((*List)(nil)):
-		return &generalListDecoder{}
+		return &generalListDecoder{policy: ext.StringPolicy}
 	case reflect.TypeOf((*Map)(nil)):
-		return &generalMapDecoder{}
+		return &generalMapDecoder{policy: ext.StringPolicy}
+	case reflect.TypeOf((*OrderedMap)(nil)):
+		return &generalOrderedMapDecoder{policy: ext.StringPolicy}
 	case reflect.TypeOf((*Struct)(nil)):
-		return &generalStructDecoder{}
+		return &generalStructDecoder{policy: ext.StringPolicy}
 	case reflect.TypeOf((*Message)(nil)):
-		return &messageDecoder{}
+		return &messageDecoder{policy: ext.StringPolicy}
 	case reflect.TypeOf((*protocol.MessageHeader)(nil)):
 		return &messageHeaderDecoder{}
+	case reflect.TypeOf((*protocol.ApplicationException)(nil)):
+		return &applicationExceptionDecoder{}
 	}
 	return nil
 }
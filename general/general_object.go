@@ -1,37 +1,101 @@
 package general
 
-import "github.com/batchcorp/thrift-iterator/protocol"
+import (
+	"encoding/json"
+	"github.com/batchcorp/thrift-iterator/protocol"
+)
 
 type Object interface {
 	Get(path ...interface{}) interface{}
+	// GetE is Get reporting missing path and type mismatch as error instead of panic
+	GetE(path ...interface{}) (interface{}, error)
 }
 
-type List []interface{}
+// List keeps the element type read, so that empty list is written back as it was.
+// ElementType is taken from the first element if not set
+type List struct {
+	ElementType protocol.TType
+	Elements    []interface{}
+}
 
 func (obj List) Get(path ...interface{}) interface{} {
 	if len(path) == 0 {
 		return obj
 	}
-	elem := obj[path[0].(int)]
+	elem := obj.Elements[path[0].(int)]
 	if len(path) == 1 {
 		return elem
 	}
 	return elem.(Object).Get(path[1:]...)
 }
 
-type Map map[interface{}]interface{}
+// MarshalJSON writes the elements only
+func (obj List) MarshalJSON() ([]byte, error) {
+	return json.Marshal(obj.Elements)
+}
+
+// Map keeps the key and element types read, they are taken from the first entry if not set
+type Map struct {
+	KeyType     protocol.TType
+	ElementType protocol.TType
+	Entries     map[interface{}]interface{}
+}
 
 func (obj Map) Get(path ...interface{}) interface{} {
 	if len(path) == 0 {
 		return obj
 	}
-	elem := obj[path[0]]
+	elem := obj.Entries[path[0]]
 	if len(path) == 1 {
 		return elem
 	}
 	return elem.(Object).Get(path[1:]...)
 }
 
+// MapEntry is one key and element of OrderedMap
+type MapEntry struct {
+	Key     interface{}
+	Element interface{}
+}
+
+// OrderedMap is the map with keys not hashable in go, which are list, set, map and struct.
+// The entries are kept in the order read
+type OrderedMap struct {
+	KeyType     protocol.TType
+	ElementType protocol.TType
+	Entries     []MapEntry
+}
+
+func (obj OrderedMap) Get(path ...interface{}) interface{} {
+	if len(path) == 0 {
+		return obj
+	}
+	elem, _ := findEntry(obj.Entries, path[0])
+	if len(path) == 1 {
+		return elem
+	}
+	return elem.(Object).Get(path[1:]...)
+}
+
+// indexOfEntry compares the keys by Equal, so that the key built by hand matches the key decoded.
+// It returns -1 if not found
+func indexOfEntry(entries []MapEntry, key interface{}) int {
+	for i, entry := range entries {
+		if Equal(entry.Key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func findEntry(entries []MapEntry, key interface{}) (interface{}, bool) {
+	i := indexOfEntry(entries, key)
+	if i == -1 {
+		return nil, false
+	}
+	return entries[i].Element, true
+}
+
 type Struct map[protocol.FieldId]interface{}
 
 func (obj Struct) Get(path ...interface{}) interface{} {
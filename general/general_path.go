@@ -0,0 +1,397 @@
+package general
+
+import (
+	"errors"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is reported when the path does not exist
+var ErrNotFound = errors.New("path not found")
+
+// pathSegment is parsed from string path, it is resolved by the container it applies to
+type pathSegment string
+
+// ParsePath parses path like "1.0.2" or "1/[0]/2". Each segment is the field id of struct,
+// the index of list or the key of map, depending on the object it applies to. [n] is always list index
+func ParsePath(path string) ([]interface{}, error) {
+	if path == "" {
+		return nil, nil
+	}
+	segments := strings.FieldsFunc(path, func(r rune) bool {
+		return r == '.' || r == '/'
+	})
+	parsed := make([]interface{}, 0, len(segments))
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, "[") && strings.HasSuffix(segment, "]") {
+			index, err := strconv.Atoi(segment[1 : len(segment)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid list index in path %q: %s", path, segment)
+			}
+			parsed = append(parsed, index)
+			continue
+		}
+		parsed = append(parsed, pathSegment(segment))
+	}
+	return parsed, nil
+}
+
+func (obj Struct) GetE(path ...interface{}) (interface{}, error) {
+	return getIn(obj, path)
+}
+
+func (obj List) GetE(path ...interface{}) (interface{}, error) {
+	return getIn(obj, path)
+}
+
+func (obj Map) GetE(path ...interface{}) (interface{}, error) {
+	return getIn(obj, path)
+}
+
+func (obj OrderedMap) GetE(path ...interface{}) (interface{}, error) {
+	return getIn(obj, path)
+}
+
+// Set puts value at path, missing structs in the middle are created
+func (obj Struct) Set(value interface{}, path ...interface{}) error {
+	if obj == nil {
+		return errors.New("set into nil general.Struct")
+	}
+	_, err := setIn(obj, value, path)
+	return err
+}
+
+// Set puts value at path, index of the list length appends to the list
+func (obj *List) Set(value interface{}, path ...interface{}) error {
+	updated, err := setIn(*obj, value, path)
+	if err == nil {
+		*obj = updated.(List)
+	}
+	return err
+}
+
+// Set puts value at path, missing structs in the middle are created
+func (obj *Map) Set(value interface{}, path ...interface{}) error {
+	updated, err := setIn(*obj, value, path)
+	if err == nil {
+		*obj = updated.(Map)
+	}
+	return err
+}
+
+// Set puts value at path, missing structs in the middle are created
+func (obj *OrderedMap) Set(value interface{}, path ...interface{}) error {
+	updated, err := setIn(*obj, value, path)
+	if err == nil {
+		*obj = updated.(OrderedMap)
+	}
+	return err
+}
+
+// Delete removes the value at path
+func (obj Struct) Delete(path ...interface{}) error {
+	_, err := deleteIn(obj, path)
+	return err
+}
+
+// Delete removes the value at path, the elements after removed list element are moved forward
+func (obj *List) Delete(path ...interface{}) error {
+	updated, err := deleteIn(*obj, path)
+	if err == nil {
+		*obj = updated.(List)
+	}
+	return err
+}
+
+// Delete removes the value at path
+func (obj *Map) Delete(path ...interface{}) error {
+	updated, err := deleteIn(*obj, path)
+	if err == nil {
+		*obj = updated.(Map)
+	}
+	return err
+}
+
+// Delete removes the value at path
+func (obj *OrderedMap) Delete(path ...interface{}) error {
+	updated, err := deleteIn(*obj, path)
+	if err == nil {
+		*obj = updated.(OrderedMap)
+	}
+	return err
+}
+
+func getIn(obj interface{}, path []interface{}) (interface{}, error) {
+	for i := range path {
+		key, err := keyOf(obj, path[i])
+		if err != nil {
+			return nil, pathError(path[:i+1], err)
+		}
+		elem, found := childOf(obj, key)
+		if !found {
+			return nil, pathError(path[:i+1], ErrNotFound)
+		}
+		obj = elem
+	}
+	return obj, nil
+}
+
+// setIn returns the container with value set, the containers are updated from bottom up
+func setIn(container interface{}, value interface{}, path []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("set with empty path")
+	}
+	key, err := keyOf(container, path[0])
+	if err != nil {
+		return nil, pathError(path[:1], err)
+	}
+	if len(path) > 1 {
+		child, found := childOf(container, key)
+		if !found {
+			if !isStructKey(path[1]) {
+				return nil, pathError(path[:1], ErrNotFound)
+			}
+			child = Struct{}
+		}
+		value, err = setIn(child, value, path[1:])
+		if err != nil {
+			return nil, prefixPath(path[0], err)
+		}
+	}
+	updated, err := put(container, key, value)
+	if err != nil {
+		return nil, pathError(path[:1], err)
+	}
+	return updated, nil
+}
+
+func deleteIn(container interface{}, path []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("delete with empty path")
+	}
+	key, err := keyOf(container, path[0])
+	if err != nil {
+		return nil, pathError(path[:1], err)
+	}
+	if len(path) == 1 {
+		updated, deleted := remove(container, key)
+		if !deleted {
+			return nil, pathError(path[:1], ErrNotFound)
+		}
+		return updated, nil
+	}
+	child, found := childOf(container, key)
+	if !found {
+		return nil, pathError(path[:1], ErrNotFound)
+	}
+	child, err = deleteIn(child, path[1:])
+	if err != nil {
+		return nil, prefixPath(path[0], err)
+	}
+	return put(container, key, child)
+}
+
+// keyOf resolves the path segment to the key used by the container
+func keyOf(container interface{}, key interface{}) (interface{}, error) {
+	switch obj := container.(type) {
+	case Struct:
+		return PathKey(protocol.TypeStruct, protocol.TypeStop, key)
+	case List:
+		return PathKey(protocol.TypeList, protocol.TypeStop, key)
+	case Map:
+		return PathKey(protocol.TypeMap, obj.KeyType, key)
+	case OrderedMap:
+		if segment, isSegment := key.(pathSegment); isSegment {
+			return nil, fmt.Errorf("general.OrderedMap can not be indexed by %q", segment)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%T is not general object", container)
+}
+
+// PathKey resolves the path element to the key of container encoded as containerType,
+// the field id of struct, the index of list or set, or the map key of keyType.
+// It is used to walk the path in encoded bytes the same way as in general objects
+func PathKey(containerType protocol.TType, keyType protocol.TType, key interface{}) (interface{}, error) {
+	segment, isSegment := key.(pathSegment)
+	switch containerType {
+	case protocol.TypeStruct:
+		if isSegment {
+			fieldId, err := strconv.ParseInt(string(segment), 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field id %q", segment)
+			}
+			return protocol.FieldId(fieldId), nil
+		}
+		if _, isFieldId := key.(protocol.FieldId); !isFieldId {
+			return nil, fmt.Errorf("general.Struct can not be indexed by %T", key)
+		}
+	case protocol.TypeList, protocol.TypeSet:
+		if isSegment {
+			index, err := strconv.Atoi(string(segment))
+			if err != nil {
+				return nil, fmt.Errorf("invalid list index %q", segment)
+			}
+			return index, nil
+		}
+		if _, isIndex := key.(int); !isIndex {
+			return nil, fmt.Errorf("general.List can not be indexed by %T", key)
+		}
+	case protocol.TypeMap:
+		if isSegment {
+			return parseKey(string(segment), keyType)
+		}
+	default:
+		return nil, fmt.Errorf("%v can not be indexed", containerType)
+	}
+	return key, nil
+}
+
+// parseKey converts the string to the map key of keyType
+func parseKey(segment string, keyType protocol.TType) (interface{}, error) {
+	var key interface{}
+	var err error
+	switch keyType {
+	case protocol.TypeString, protocol.TypeStop:
+		key = segment
+	case protocol.TypeBool:
+		key, err = strconv.ParseBool(segment)
+	case protocol.TypeI08:
+		var n int64
+		n, err = strconv.ParseInt(segment, 10, 8)
+		key = int8(n)
+	case protocol.TypeI16:
+		var n int64
+		n, err = strconv.ParseInt(segment, 10, 16)
+		key = int16(n)
+	case protocol.TypeI32:
+		var n int64
+		n, err = strconv.ParseInt(segment, 10, 32)
+		key = int32(n)
+	case protocol.TypeI64:
+		key, err = strconv.ParseInt(segment, 10, 64)
+	case protocol.TypeDouble:
+		key, err = strconv.ParseFloat(segment, 64)
+	default:
+		return nil, fmt.Errorf("map key of %v can not be parsed from %q", keyType, segment)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid map key %q of %v", segment, keyType)
+	}
+	return key, nil
+}
+
+func isStructKey(key interface{}) bool {
+	switch key.(type) {
+	case protocol.FieldId, pathSegment:
+		return true
+	}
+	return false
+}
+
+func childOf(container interface{}, key interface{}) (interface{}, bool) {
+	switch obj := container.(type) {
+	case Struct:
+		elem, found := obj[key.(protocol.FieldId)]
+		return elem, found
+	case List:
+		index := key.(int)
+		if index < 0 || index >= len(obj.Elements) {
+			return nil, false
+		}
+		return obj.Elements[index], true
+	case Map:
+		elem, found := obj.Entries[key]
+		return elem, found
+	case OrderedMap:
+		return findEntry(obj.Entries, key)
+	}
+	return nil, false
+}
+
+func put(container interface{}, key interface{}, value interface{}) (interface{}, error) {
+	switch obj := container.(type) {
+	case Struct:
+		obj[key.(protocol.FieldId)] = value
+		return obj, nil
+	case List:
+		index := key.(int)
+		switch {
+		case index >= 0 && index < len(obj.Elements):
+			obj.Elements[index] = value
+		case index == len(obj.Elements):
+			obj.Elements = append(obj.Elements, value)
+		default:
+			return nil, fmt.Errorf("index %d out of range of %d elements", index, len(obj.Elements))
+		}
+		return obj, nil
+	case Map:
+		if obj.Entries == nil {
+			obj.Entries = map[interface{}]interface{}{}
+		}
+		obj.Entries[key] = value
+		return obj, nil
+	case OrderedMap:
+		if i := indexOfEntry(obj.Entries, key); i != -1 {
+			obj.Entries[i].Element = value
+			return obj, nil
+		}
+		obj.Entries = append(obj.Entries, MapEntry{Key: key, Element: value})
+		return obj, nil
+	}
+	return nil, fmt.Errorf("%T is not general object", container)
+}
+
+func remove(container interface{}, key interface{}) (interface{}, bool) {
+	switch obj := container.(type) {
+	case Struct:
+		fieldId := key.(protocol.FieldId)
+		_, found := obj[fieldId]
+		delete(obj, fieldId)
+		return obj, found
+	case List:
+		index := key.(int)
+		if index < 0 || index >= len(obj.Elements) {
+			return nil, false
+		}
+		obj.Elements = append(obj.Elements[:index:index], obj.Elements[index+1:]...)
+		return obj, true
+	case Map:
+		_, found := obj.Entries[key]
+		delete(obj.Entries, key)
+		return obj, found
+	case OrderedMap:
+		if i := indexOfEntry(obj.Entries, key); i != -1 {
+			obj.Entries = append(obj.Entries[:i:i], obj.Entries[i+1:]...)
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+type pathErr struct {
+	path []interface{}
+	err  error
+}
+
+func (err *pathErr) Error() string {
+	return FormatPath(err.path) + ": " + err.err.Error()
+}
+
+func (err *pathErr) Unwrap() error {
+	return err.err
+}
+
+func pathError(path []interface{}, err error) error {
+	return &pathErr{path: append([]interface{}(nil), path...), err: err}
+}
+
+// prefixPath puts the parent key before the path of error reported by child
+func prefixPath(key interface{}, err error) error {
+	if childErr, isPathErr := err.(*pathErr); isPathErr {
+		return &pathErr{path: append([]interface{}{key}, childErr.path...), err: childErr.err}
+	}
+	return pathError([]interface{}{key}, err)
+}
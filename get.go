@@ -0,0 +1,98 @@
+package thrifter
+
+import (
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+)
+
+// Get decodes the value at path in the encoded struct, the values before it are skipped without decoding.
+// The path is same as general.Struct Get, it can also be parsed by general.ParsePath
+func (cfg *frozenConfig) Get(buf []byte, path ...interface{}) (interface{}, error) {
+	iter := cfg.BorrowIterator(nil, buf)
+	defer cfg.ReturnIterator(iter)
+	return cfg.get(iter, path)
+}
+
+// GetMessage is Get with the path in the arguments of the encoded message
+func (cfg *frozenConfig) GetMessage(buf []byte, path ...interface{}) (interface{}, error) {
+	iter := cfg.BorrowIterator(nil, buf)
+	defer cfg.ReturnIterator(iter)
+	iter.ReadMessageHeader()
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+	return cfg.get(iter, path)
+}
+
+func (cfg *frozenConfig) get(iter spi.Iterator, path []interface{}) (interface{}, error) {
+	ttype := protocol.TypeStruct
+	for i, key := range path {
+		var err error
+		ttype, err = seek(iter, ttype, key)
+		if iter.Error() != nil {
+			return nil, iter.Error()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", general.FormatPath(path[:i+1]), err)
+		}
+	}
+	val := general.ReadWithPolicy(iter, ttype, cfg.stringPolicy)
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+	return val, nil
+}
+
+// seek moves iter to the element at key of the container, returns the type of element
+func seek(iter spi.Iterator, containerType protocol.TType, key interface{}) (protocol.TType, error) {
+	switch containerType {
+	case protocol.TypeStruct:
+		resolved, err := general.PathKey(containerType, protocol.TypeStop, key)
+		if err != nil {
+			return protocol.TypeStop, err
+		}
+		iter.ReadStructHeader()
+		for iter.Error() == nil {
+			fieldType, fieldId := iter.ReadStructField()
+			if fieldType == protocol.TypeStop {
+				break
+			}
+			if fieldId == resolved.(protocol.FieldId) {
+				return fieldType, nil
+			}
+			iter.Discard(fieldType)
+		}
+	case protocol.TypeList, protocol.TypeSet:
+		resolved, err := general.PathKey(containerType, protocol.TypeStop, key)
+		if err != nil {
+			return protocol.TypeStop, err
+		}
+		index := resolved.(int)
+		elemType, length := iter.ReadListHeader()
+		if index < 0 || index >= length {
+			break
+		}
+		for i := 0; i < index && iter.Error() == nil; i++ {
+			iter.Discard(elemType)
+		}
+		return elemType, nil
+	case protocol.TypeMap:
+		keyType, elemType, length := iter.ReadMapHeader()
+		resolved, err := general.PathKey(containerType, keyType, key)
+		if err != nil {
+			return protocol.TypeStop, err
+		}
+		for i := 0; i < length && iter.Error() == nil; i++ {
+			// map keys are decoded to compare, same as general.Map
+			if general.Equal(general.Read(iter, keyType), resolved) {
+				return elemType, nil
+			}
+			iter.Discard(elemType)
+		}
+	default:
+		return protocol.TypeStop, fmt.Errorf("%v can not be indexed", containerType)
+	}
+	return protocol.TypeStop, general.ErrNotFound
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Logging logs the method, seqid, elapsed time and error of each call, log.Default() is used if logger is nil
+func Logging(logger *log.Logger) Interceptor {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(ctx context.Context, call *Call, next Handler) (interface{}, error) {
+		start := time.Now()
+		result, err := next(ctx, call)
+		elapsed := time.Since(start)
+		if err != nil {
+			logger.Printf("thrift call %s seqid %d failed in %v: %v",
+				call.Header.MessageName, call.Header.SeqId, elapsed, err)
+		} else {
+			logger.Printf("thrift call %s seqid %d done in %v",
+				call.Header.MessageName, call.Header.SeqId, elapsed)
+		}
+		return result, err
+	}
+}
+
+// Timing reports the elapsed time of each call to observe, which can feed metrics
+func Timing(observe func(method string, elapsed time.Duration, err error)) Interceptor {
+	return func(ctx context.Context, call *Call, next Handler) (interface{}, error) {
+		start := time.Now()
+		result, err := next(ctx, call)
+		observe(call.Header.MessageName, time.Since(start), err)
+		return result, err
+	}
+}
@@ -0,0 +1,95 @@
+// Package middleware defines interceptors shared by the server, client and proxy packages
+package middleware
+
+import (
+	"context"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"reflect"
+)
+
+// Call is the message being processed, the arguments are converted only when asked
+type Call struct {
+	Header protocol.MessageHeader
+	api    thrifter.API
+	args   interface{}
+	raw    raw.Struct
+}
+
+// NewCall keeps args as it is, api is used to convert args to other forms
+func NewCall(api thrifter.API, header protocol.MessageHeader, args interface{}) *Call {
+	if api == nil {
+		api = thrifter.DefaultConfig
+	}
+	call := &Call{Header: header, api: api, args: args}
+	if rawArgs, isRaw := args.(raw.Struct); isRaw {
+		call.raw = rawArgs
+	}
+	return call
+}
+
+// Args is raw.Struct in proxy, the bound struct in server and client
+func (call *Call) Args() interface{} {
+	return call.args
+}
+
+// RawArgs returns the arguments as raw.Struct. In proxy the arguments are modified by modifying the returned value,
+// in server and client the returned value is a copy
+func (call *Call) RawArgs() (raw.Struct, error) {
+	if call.raw != nil {
+		return call.raw, nil
+	}
+	buf, err := call.api.Marshal(call.args)
+	if err != nil {
+		return nil, err
+	}
+	var rawArgs raw.Struct
+	if err := call.api.Unmarshal(buf, &rawArgs); err != nil {
+		return nil, err
+	}
+	call.raw = rawArgs
+	return rawArgs, nil
+}
+
+// BindArgs decodes the arguments into val, which is a pointer to struct
+func (call *Call) BindArgs(val interface{}) error {
+	if call.raw == nil && call.args != nil {
+		argsVal := reflect.ValueOf(call.args)
+		if argsVal.Type() == reflect.TypeOf(val) {
+			reflect.ValueOf(val).Elem().Set(argsVal.Elem())
+			return nil
+		}
+	}
+	buf, err := call.api.Marshal(call.args)
+	if err != nil {
+		return err
+	}
+	return call.api.Unmarshal(buf, val)
+}
+
+// Handler processes the call, the result is the bound result struct in server and client, opaque in proxy
+type Handler func(ctx context.Context, call *Call) (result interface{}, err error)
+
+// Interceptor processes the call around next, it short-circuits by returning error without calling next.
+// *protocol.ApplicationException returned is replied as it is, other error is replied as INTERNAL_ERROR
+type Interceptor func(ctx context.Context, call *Call, next Handler) (result interface{}, err error)
+
+// Wrap makes handler called after interceptors, the first interceptor is the outermost
+func Wrap(handler Handler, interceptors ...Interceptor) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := handler
+		handler = func(ctx context.Context, call *Call) (interface{}, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return handler
+}
+
+// Chain combines interceptors into one, the first interceptor is the outermost
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, call *Call, next Handler) (interface{}, error) {
+		return Wrap(next, interceptors...)(ctx, call)
+	}
+}
@@ -0,0 +1,326 @@
+package thrifter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"reflect"
+)
+
+// patch is one change applied to the encoded bytes, the value is not set if deleting
+type patch struct {
+	cfg      *frozenConfig
+	path     []interface{}
+	value    interface{}
+	valType  protocol.TType
+	encoder  spi.ValEncoder
+	deleting bool
+}
+
+// Set patches buf to have value at path, replacing the value there or inserting it.
+// Missing structs in the middle are created, list index of the list length appends to the list.
+// The bytes not on the path are copied as they are, so that field order and unknown fields are kept
+func (cfg *frozenConfig) Set(buf []byte, value interface{}, path ...interface{}) ([]byte, error) {
+	p, err := cfg.setPatch(value, path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.patch(buf, p, false)
+}
+
+// SetMessage is Set with the path in the arguments of the encoded message, the header is copied as it is
+func (cfg *frozenConfig) SetMessage(buf []byte, value interface{}, path ...interface{}) ([]byte, error) {
+	p, err := cfg.setPatch(value, path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.patch(buf, p, true)
+}
+
+// Delete patches buf to remove the value at path, the bytes not on the path are copied as they are
+func (cfg *frozenConfig) Delete(buf []byte, path ...interface{}) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, errors.New("delete with empty path")
+	}
+	return cfg.patch(buf, &patch{cfg: cfg, path: path, deleting: true}, false)
+}
+
+// DeleteMessage is Delete with the path in the arguments of the encoded message
+func (cfg *frozenConfig) DeleteMessage(buf []byte, path ...interface{}) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, errors.New("delete with empty path")
+	}
+	return cfg.patch(buf, &patch{cfg: cfg, path: path, deleting: true}, true)
+}
+
+func (cfg *frozenConfig) setPatch(value interface{}, path []interface{}) (*patch, error) {
+	if len(path) == 0 {
+		return nil, errors.New("set with empty path")
+	}
+	if value == nil {
+		// thrift has no null, the field should be deleted instead
+		return nil, errors.New("set nil value, use Delete to remove")
+	}
+	encoder := cfg.cachedEncoderOf(value)
+	return &patch{cfg: cfg, path: path, value: value, valType: encoder.ThriftType(), encoder: encoder}, nil
+}
+
+func (cfg *frozenConfig) patch(buf []byte, p *patch, message bool) ([]byte, error) {
+	iter := cfg.BorrowIterator(nil, buf)
+	defer cfg.ReturnIterator(iter)
+	stream := cfg.BorrowStream(nil)
+	defer cfg.ReturnStream(stream)
+	if message {
+		stream.Write(iter.SkipMessageHeader(nil))
+		if iter.Error() != nil {
+			return nil, iter.Error()
+		}
+	}
+	err := p.patchValue(iter, stream, protocol.TypeStruct, 0)
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stream.Error() != nil {
+		return nil, stream.Error()
+	}
+	return append([]byte(nil), stream.Buffer()...), nil
+}
+
+// patchValue copies the container of ttype from iter to stream, with the element at path[depth] patched
+func (p *patch) patchValue(iter spi.Iterator, stream spi.Stream, ttype protocol.TType, depth int) error {
+	switch ttype {
+	case protocol.TypeStruct:
+		key, err := general.PathKey(ttype, protocol.TypeStop, p.path[depth])
+		if err != nil {
+			return p.pathError(depth, err)
+		}
+		return p.patchStruct(iter, stream, key.(protocol.FieldId), depth)
+	case protocol.TypeList, protocol.TypeSet:
+		key, err := general.PathKey(ttype, protocol.TypeStop, p.path[depth])
+		if err != nil {
+			return p.pathError(depth, err)
+		}
+		return p.patchList(iter, stream, key.(int), depth)
+	case protocol.TypeMap:
+		// the key is resolved after the key type is read
+		return p.patchMap(iter, stream, depth)
+	}
+	return p.pathError(depth, fmt.Errorf("%v can not be indexed", ttype))
+}
+
+func (p *patch) patchStruct(iter spi.Iterator, stream spi.Stream, fieldId protocol.FieldId, depth int) error {
+	iter.ReadStructHeader()
+	stream.WriteStructHeader()
+	found := false
+	for iter.Error() == nil {
+		fieldType, currentId := iter.ReadStructField()
+		if fieldType == protocol.TypeStop {
+			break
+		}
+		if currentId != fieldId || found {
+			raw.WriteField(stream, fieldType, currentId, iter.Skip(fieldType, nil))
+			continue
+		}
+		found = true
+		if err := p.patchElement(iter, stream, fieldType, depth, func(elemType protocol.TType) {
+			stream.WriteStructField(elemType, fieldId)
+		}); err != nil {
+			return err
+		}
+	}
+	if !found {
+		if p.deleting {
+			return p.pathError(depth, general.ErrNotFound)
+		}
+		if err := p.insertField(stream, fieldId, depth); err != nil {
+			return err
+		}
+	}
+	stream.WriteStructFieldStop()
+	return nil
+}
+
+// insertField writes the new field, with the structs in the middle of path created
+func (p *patch) insertField(stream spi.Stream, fieldId protocol.FieldId, depth int) error {
+	if depth == len(p.path)-1 {
+		stream.WriteStructField(p.valType, fieldId)
+		p.encoder.Encode(p.value, stream)
+		return nil
+	}
+	childKey, err := general.PathKey(protocol.TypeStruct, protocol.TypeStop, p.path[depth+1])
+	if err != nil {
+		return p.pathError(depth, general.ErrNotFound)
+	}
+	stream.WriteStructField(protocol.TypeStruct, fieldId)
+	stream.WriteStructHeader()
+	if err := p.insertField(stream, childKey.(protocol.FieldId), depth+1); err != nil {
+		return err
+	}
+	stream.WriteStructFieldStop()
+	return nil
+}
+
+func (p *patch) patchList(iter spi.Iterator, stream spi.Stream, index int, depth int) error {
+	elemType, length := iter.ReadListHeader()
+	if index < 0 || index > length || (index == length && (p.deleting || depth < len(p.path)-1)) {
+		return p.pathError(depth, general.ErrNotFound)
+	}
+	newLength := length
+	if p.isLast(depth) {
+		if p.deleting {
+			newLength--
+		} else if index == length {
+			newLength++
+		}
+		if length == 0 {
+			// the element type of empty list is not known
+			elemType = p.valType
+		}
+	}
+	stream.WriteListHeader(elemType, newLength)
+	for i := 0; i < length && iter.Error() == nil; i++ {
+		if i != index {
+			stream.Write(iter.Skip(elemType, nil))
+			continue
+		}
+		if err := p.patchElement(iter, stream, elemType, depth, nil); err != nil {
+			return err
+		}
+	}
+	if index == length {
+		return p.writeElement(stream, elemType, depth)
+	}
+	return nil
+}
+
+func (p *patch) patchMap(iter spi.Iterator, stream spi.Stream, depth int) error {
+	keyType, elemType, length := iter.ReadMapHeader()
+	if length == 0 {
+		// the key and element types of empty map are not known
+		keyType, elemType = protocol.TypeStop, p.valType
+	}
+	key, err := general.PathKey(protocol.TypeMap, keyType, p.path[depth])
+	if err != nil {
+		return p.pathError(depth, err)
+	}
+	if keyType == protocol.TypeStop {
+		keyType = p.cfg.thriftTypeOf(key)
+	}
+	// the length is known after all entries are read
+	entries := stream.Spawn()
+	newLength := 0
+	found := false
+	for i := 0; i < length && iter.Error() == nil; i++ {
+		keyBuf := iter.Skip(keyType, nil)
+		if found || !p.cfg.keyEqual(keyBuf, keyType, key) {
+			entries.Write(keyBuf)
+			entries.Write(iter.Skip(elemType, nil))
+			newLength++
+			continue
+		}
+		found = true
+		if p.deleting && p.isLast(depth) {
+			iter.Discard(elemType)
+			continue
+		}
+		entries.Write(keyBuf)
+		if err := p.patchElement(iter, entries, elemType, depth, nil); err != nil {
+			return err
+		}
+		newLength++
+	}
+	if !found {
+		if p.deleting || !p.isLast(depth) {
+			return p.pathError(depth, general.ErrNotFound)
+		}
+		if err := p.cfg.writeKey(entries, keyType, key); err != nil {
+			return p.pathError(depth, err)
+		}
+		if err := p.writeElement(entries, elemType, depth); err != nil {
+			return err
+		}
+		newLength++
+	}
+	if entries.Error() != nil {
+		return entries.Error()
+	}
+	stream.WriteMapHeader(keyType, elemType, newLength)
+	stream.Write(entries.Buffer())
+	return nil
+}
+
+// patchElement replaces the element at path[depth] or patches within it, writeHeader is called with the element type
+// before the element is written, for struct field header
+func (p *patch) patchElement(iter spi.Iterator, stream spi.Stream, elemType protocol.TType, depth int,
+	writeHeader func(elemType protocol.TType)) error {
+	if !p.isLast(depth) {
+		if writeHeader != nil {
+			writeHeader(elemType)
+		}
+		return p.patchValue(iter, stream, elemType, depth+1)
+	}
+	iter.Discard(elemType)
+	if p.deleting {
+		return nil
+	}
+	if writeHeader != nil {
+		// struct field can change its type
+		writeHeader(p.valType)
+		p.encoder.Encode(p.value, stream)
+		return nil
+	}
+	return p.writeElement(stream, elemType, depth)
+}
+
+// writeElement writes the value as element of list or map, the type must be same as the other elements
+func (p *patch) writeElement(stream spi.Stream, elemType protocol.TType, depth int) error {
+	// set is written same as list
+	if p.valType != elemType && !(p.valType == protocol.TypeList && elemType == protocol.TypeSet) {
+		return p.pathError(depth, fmt.Errorf("expected element of type %v but got %v", elemType, p.valType))
+	}
+	p.encoder.Encode(p.value, stream)
+	return nil
+}
+
+func (p *patch) isLast(depth int) bool {
+	return depth == len(p.path)-1
+}
+
+func (p *patch) pathError(depth int, err error) error {
+	return fmt.Errorf("%s: %w", general.FormatPath(p.path[:depth+1]), err)
+}
+
+// keyEqual decodes the encoded map key to compare with key
+func (cfg *frozenConfig) keyEqual(keyBuf []byte, keyType protocol.TType, key interface{}) bool {
+	iter := cfg.BorrowIterator(nil, keyBuf)
+	defer cfg.ReturnIterator(iter)
+	return general.Equal(general.Read(iter, keyType), key)
+}
+
+func (cfg *frozenConfig) writeKey(stream spi.Stream, keyType protocol.TType, key interface{}) error {
+	if keyType != cfg.thriftTypeOf(key) {
+		return fmt.Errorf("expected key of type %v but got %v", keyType, cfg.thriftTypeOf(key))
+	}
+	cfg.cachedEncoderOf(key).Encode(key, stream)
+	return nil
+}
+
+func (cfg *frozenConfig) thriftTypeOf(val interface{}) protocol.TType {
+	return cfg.cachedEncoderOf(val).ThriftType()
+}
+
+func (cfg *frozenConfig) cachedEncoderOf(val interface{}) spi.ValEncoder {
+	valType := reflect.TypeOf(val)
+	encoder := cfg.getGenEncoder(valType)
+	if encoder == nil {
+		encoder = cfg.encoderOf(valType)
+		cfg.addGenEncoder(valType, encoder)
+	}
+	return encoder
+}
@@ -0,0 +1,43 @@
+package thrifter
+
+import (
+	"github.com/batchcorp/thrift-iterator/spi"
+	"io"
+)
+
+// maxPooledBuffer limits the buffer kept by pooled stream, larger buffer is left to GC
+const maxPooledBuffer = 1 << 20
+
+func (cfg *frozenConfig) BorrowStream(writer io.Writer) spi.Stream {
+	pooled := cfg.streamPool.Get()
+	if pooled == nil {
+		return cfg.NewStream(writer, nil)
+	}
+	stream := pooled.(spi.Stream)
+	stream.Reset(writer)
+	return stream
+}
+
+func (cfg *frozenConfig) ReturnStream(stream spi.Stream) {
+	if cap(stream.Buffer()) > maxPooledBuffer {
+		return
+	}
+	stream.Reset(nil)
+	cfg.streamPool.Put(stream)
+}
+
+func (cfg *frozenConfig) BorrowIterator(reader io.Reader, buf []byte) spi.Iterator {
+	pooled := cfg.iteratorPool.Get()
+	if pooled == nil {
+		return cfg.NewIterator(reader, buf)
+	}
+	iter := pooled.(spi.Iterator)
+	iter.Reset(reader, buf)
+	return iter
+}
+
+func (cfg *frozenConfig) ReturnIterator(iter spi.Iterator) {
+	// do not keep the input alive
+	iter.Reset(nil, nil)
+	cfg.iteratorPool.Put(iter)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/batchcorp/thrift-iterator/spi"
 	"io"
 	"math"
+	"unsafe"
 )
 
 type Iterator struct {
@@ -15,6 +16,12 @@ type Iterator struct {
 	preread []byte
 	skipped []byte
 	err     error
+
+	// zeroCopyBinary and zeroCopyString are set by SetZeroCopy
+	zeroCopyBinary bool
+	zeroCopyString bool
+	depth          int
+	maxDepth       int
 }
 
 func NewIterator(provider spi.ValDecoderProvider, reader io.Reader, buf []byte) *Iterator {
@@ -23,15 +30,29 @@ func NewIterator(provider spi.ValDecoderProvider, reader io.Reader, buf []byte)
 		reader:             reader,
 		tmp:                make([]byte, 8),
 		preread:            buf,
+		maxDepth:           protocol.DefaultMaxDepth,
 	}
 }
 
+// SetMaxDepth limits the nesting of structs and the containers counted by EnterContainer, non-positive means no limit
+func (iter *Iterator) SetMaxDepth(maxDepth int) {
+	iter.maxDepth = maxDepth
+}
+
 func (iter *Iterator) readByte() byte {
+	if iter.err != nil {
+		// stop consuming the input, so that the nested decoders unwind
+		return 0
+	}
 	tmp := iter.tmp[:1]
 	if len(iter.preread) > 0 {
 		tmp[0] = iter.preread[0]
 		iter.preread = iter.preread[1:]
 	} else {
+		if iter.reader == nil {
+			iter.ReportError("read", io.EOF.Error())
+			return 0
+		}
 		_, err := iter.reader.Read(tmp)
 		if err != nil {
 			iter.ReportError("read", err.Error())
@@ -46,6 +67,12 @@ func (iter *Iterator) readByte() byte {
 
 func (iter *Iterator) readSmall(nBytes int) []byte {
 	tmp := iter.tmp[:nBytes]
+	if iter.err != nil {
+		for i := 0; i < len(tmp); i++ {
+			tmp[i] = 0
+		}
+		return tmp
+	}
 	wantBytes := nBytes
 	if len(iter.preread) > 0 {
 		if len(iter.preread) > nBytes {
@@ -60,7 +87,12 @@ func (iter *Iterator) readSmall(nBytes int) []byte {
 		}
 	}
 	if wantBytes > 0 {
-		_, err := io.ReadFull(iter.reader, tmp[nBytes-wantBytes:nBytes])
+		var err error
+		if iter.reader == nil {
+			err = io.ErrUnexpectedEOF
+		} else {
+			_, err = io.ReadFull(iter.reader, tmp[nBytes-wantBytes:nBytes])
+		}
 		if err != nil {
 			for i := 0; i < len(tmp); i++ {
 				tmp[i] = 0
@@ -84,7 +116,10 @@ func (iter *Iterator) readLarge(nBytes int) []byte {
 }
 
 func (iter *Iterator) Spawn() spi.Iterator {
-	return NewIterator(iter.ValDecoderProvider, nil, nil)
+	spawned := NewIterator(iter.ValDecoderProvider, nil, nil)
+	spawned.SetZeroCopy(iter.zeroCopyBinary, iter.zeroCopyString)
+	spawned.maxDepth = iter.maxDepth
+	return spawned
 }
 
 func (iter *Iterator) Error() error {
@@ -101,6 +136,8 @@ func (iter *Iterator) Reset(reader io.Reader, buf []byte) {
 	iter.reader = reader
 	iter.preread = buf
 	iter.err = nil
+	iter.skipped = nil
+	iter.depth = 0
 }
 
 func (iter *Iterator) ReadMessageHeader() protocol.MessageHeader {
@@ -121,13 +158,28 @@ func (iter *Iterator) ReadMessageHeader() protocol.MessageHeader {
 }
 
 func (iter *Iterator) ReadStructHeader() {
-	// noop
+	iter.depth++
+	if iter.maxDepth > 0 && iter.depth > iter.maxDepth {
+		iter.ReportError("ReadStructHeader", fmt.Sprintf("struct nested deeper than %d", iter.maxDepth))
+	}
+}
+
+func (iter *Iterator) EnterContainer() {
+	iter.depth++
+	if iter.maxDepth > 0 && iter.depth > iter.maxDepth {
+		iter.ReportError("EnterContainer", fmt.Sprintf("container nested deeper than %d", iter.maxDepth))
+	}
+}
+
+func (iter *Iterator) LeaveContainer() {
+	iter.depth--
 }
 
 func (iter *Iterator) ReadStructField() (fieldType protocol.TType, fieldId protocol.FieldId) {
 	firstByte := iter.readByte()
 	fieldType = protocol.TType(firstByte)
 	if fieldType == protocol.TypeStop {
+		iter.depth--
 		return protocol.TypeStop, 0
 	}
 	fieldId = protocol.FieldId(iter.ReadUint16())
@@ -202,13 +254,40 @@ func (iter *Iterator) ReadFloat64() float64 {
 }
 
 func (iter *Iterator) ReadString() string {
-	length := iter.ReadUint32()
-	return string(iter.readLarge(int(length)))
+	length := int(iter.ReadUint32())
+	if iter.zeroCopyString {
+		if buf := iter.alias(length); buf != nil {
+			return *(*string)(unsafe.Pointer(&buf))
+		}
+	}
+	return string(iter.readLarge(length))
 }
 
 func (iter *Iterator) ReadBinary() []byte {
-	length := iter.ReadUint32()
+	length := int(iter.ReadUint32())
+	if iter.zeroCopyBinary {
+		if buf := iter.alias(length); buf != nil {
+			return buf
+		}
+	}
 	tmp := make([]byte, length)
-	copy(tmp, iter.readLarge(int(length)))
+	copy(tmp, iter.readLarge(length))
 	return tmp
 }
+
+// SetZeroCopy makes binary, and string if zeroCopyString, decoded from the buffer alias the buffer
+// instead of being copied. Reading from io.Reader or skipping still copies
+func (iter *Iterator) SetZeroCopy(zeroCopyBinary bool, zeroCopyString bool) {
+	iter.zeroCopyBinary = zeroCopyBinary
+	iter.zeroCopyString = zeroCopyString
+}
+
+// alias returns nil if the bytes are not all in the buffer
+func (iter *Iterator) alias(nBytes int) []byte {
+	if nBytes <= 0 || len(iter.preread) < nBytes || iter.skipped != nil {
+		return nil
+	}
+	buf := iter.preread[:nBytes:nBytes]
+	iter.preread = iter.preread[nBytes:]
+	return buf
+}
@@ -10,9 +10,11 @@ import (
 
 type Stream struct {
 	spi.ValEncoderProvider
-	writer io.Writer
-	buf    []byte
-	err    error
+	writer   io.Writer
+	buf      []byte
+	err      error
+	depth    int
+	maxDepth int
 }
 
 func NewStream(provider spi.ValEncoderProvider, writer io.Writer, buf []byte) *Stream {
@@ -20,12 +22,19 @@ func NewStream(provider spi.ValEncoderProvider, writer io.Writer, buf []byte) *S
 		ValEncoderProvider: provider,
 		writer:             writer,
 		buf:                buf,
+		maxDepth:           protocol.DefaultMaxDepth,
 	}
 }
 
+// SetMaxDepth limits the nesting of structs, non-positive means no limit
+func (stream *Stream) SetMaxDepth(maxDepth int) {
+	stream.maxDepth = maxDepth
+}
+
 func (stream *Stream) Spawn() spi.Stream {
 	return &Stream{
 		ValEncoderProvider: stream.ValEncoderProvider,
+		maxDepth:           stream.maxDepth,
 	}
 }
 
@@ -47,6 +56,7 @@ func (stream *Stream) Reset(writer io.Writer) {
 	stream.writer = writer
 	stream.err = nil
 	stream.buf = stream.buf[:0]
+	stream.depth = 0
 }
 
 func (stream *Stream) Flush() {
@@ -85,6 +95,10 @@ func (stream *Stream) WriteListHeader(elemType protocol.TType, length int) {
 }
 
 func (stream *Stream) WriteStructHeader() {
+	stream.depth++
+	if stream.maxDepth > 0 && stream.depth > stream.maxDepth {
+		stream.ReportError("WriteStructHeader", fmt.Sprintf("struct nested deeper than %d", stream.maxDepth))
+	}
 }
 
 func (stream *Stream) WriteStructField(fieldType protocol.TType, fieldId protocol.FieldId) {
@@ -93,6 +107,7 @@ func (stream *Stream) WriteStructField(fieldType protocol.TType, fieldId protoco
 
 func (stream *Stream) WriteStructFieldStop() {
 	stream.buf = append(stream.buf, byte(protocol.TypeStop))
+	stream.depth--
 }
 
 func (stream *Stream) WriteMapHeader(keyType protocol.TType, elemType protocol.TType, length int) {
@@ -7,7 +7,9 @@ import (
 
 func (iter *Iterator) Discard(ttype protocol.TType) {
 	switch ttype {
-	case protocol.TypeBool, protocol.TypeI08:
+	case protocol.TypeBool:
+		iter.ReadBool()
+	case protocol.TypeI08:
 		iter.ReadInt8()
 	case protocol.TypeI16:
 		iter.ReadInt16()
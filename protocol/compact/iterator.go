@@ -7,6 +7,7 @@ import (
 	"github.com/batchcorp/thrift-iterator/spi"
 	"io"
 	"math"
+	"unsafe"
 )
 
 type Iterator struct {
@@ -16,10 +17,22 @@ type Iterator struct {
 	preread []byte
 	skipped []byte
 
-	err              error
-	fieldIdStack     []protocol.FieldId
-	lastFieldId      protocol.FieldId
+	err            error
+	containerDepth int
+	fieldIdStack   []protocol.FieldId
+	lastFieldId    protocol.FieldId
+	// pendingBoolField is the value of the bool field whose header was just read, 1 for true and 2 for false.
+	// compact keeps the value of bool field in its header, so the field value is pending until the next read,
+	// which is always ReadBool or Skip of the field. Both consume it, so that bool elements of containers,
+	// which take one byte each, are never mistaken for it
 	pendingBoolField uint8
+	// version is set by SetVersion, bigEndianDouble follows the version of message header read
+	version         byte
+	bigEndianDouble bool
+	// zeroCopyBinary and zeroCopyString are set by SetZeroCopy
+	zeroCopyBinary bool
+	zeroCopyString bool
+	maxDepth       int
 }
 
 func NewIterator(provider spi.ValDecoderProvider, reader io.Reader, buf []byte) *Iterator {
@@ -28,15 +41,37 @@ func NewIterator(provider spi.ValDecoderProvider, reader io.Reader, buf []byte)
 		reader:             reader,
 		tmp:                make([]byte, 8),
 		preread:            buf,
+		version:            protocol.COMPACT_VERSION,
+		maxDepth:           protocol.DefaultMaxDepth,
 	}
 }
 
+// SetMaxDepth limits the nesting of structs and the containers counted by EnterContainer, non-positive means no limit
+func (iter *Iterator) SetMaxDepth(maxDepth int) {
+	iter.maxDepth = maxDepth
+}
+
+// SetVersion decides how double is decoded before any message header is read,
+// protocol.COMPACT_VERSON_BE decodes double as big endian
+func (iter *Iterator) SetVersion(version byte) {
+	iter.version = version
+	iter.bigEndianDouble = version == protocol.COMPACT_VERSON_BE
+}
+
 func (iter *Iterator) readByte() byte {
+	if iter.err != nil {
+		// stop consuming the input, so that the nested decoders unwind
+		return 0
+	}
 	tmp := iter.tmp[:1]
 	if len(iter.preread) > 0 {
 		tmp[0] = iter.preread[0]
 		iter.preread = iter.preread[1:]
 	} else {
+		if iter.reader == nil {
+			iter.ReportError("read", io.EOF.Error())
+			return 0
+		}
 		_, err := iter.reader.Read(tmp)
 		if err != nil {
 			iter.ReportError("read", err.Error())
@@ -51,6 +86,12 @@ func (iter *Iterator) readByte() byte {
 
 func (iter *Iterator) readSmall(nBytes int) []byte {
 	tmp := iter.tmp[:nBytes]
+	if iter.err != nil {
+		for i := 0; i < len(tmp); i++ {
+			tmp[i] = 0
+		}
+		return tmp
+	}
 	wantBytes := nBytes
 	if len(iter.preread) > 0 {
 		if len(iter.preread) > nBytes {
@@ -65,7 +106,12 @@ func (iter *Iterator) readSmall(nBytes int) []byte {
 		}
 	}
 	if wantBytes > 0 {
-		_, err := io.ReadFull(iter.reader, tmp[nBytes-wantBytes:nBytes])
+		var err error
+		if iter.reader == nil {
+			err = io.ErrUnexpectedEOF
+		} else {
+			_, err = io.ReadFull(iter.reader, tmp[nBytes-wantBytes:nBytes])
+		}
 		if err != nil {
 			for i := 0; i < len(tmp); i++ {
 				tmp[i] = 0
@@ -110,7 +156,12 @@ func (iter *Iterator) readVarInt64() int64 {
 }
 
 func (iter *Iterator) Spawn() spi.Iterator {
-	return NewIterator(iter.ValDecoderProvider, nil, nil)
+	spawned := NewIterator(iter.ValDecoderProvider, nil, nil)
+	spawned.version = iter.version
+	spawned.bigEndianDouble = iter.bigEndianDouble
+	spawned.SetZeroCopy(iter.zeroCopyBinary, iter.zeroCopyString)
+	spawned.maxDepth = iter.maxDepth
+	return spawned
 }
 
 func (iter *Iterator) Error() error {
@@ -127,6 +178,12 @@ func (iter *Iterator) Reset(reader io.Reader, buf []byte) {
 	iter.reader = reader
 	iter.preread = buf
 	iter.err = nil
+	iter.skipped = nil
+	iter.containerDepth = 0
+	iter.fieldIdStack = iter.fieldIdStack[:0]
+	iter.lastFieldId = 0
+	iter.pendingBoolField = 0
+	iter.bigEndianDouble = iter.version == protocol.COMPACT_VERSON_BE
 }
 
 func (iter *Iterator) ReadMessageHeader() protocol.MessageHeader {
@@ -138,8 +195,14 @@ func (iter *Iterator) ReadMessageHeader() protocol.MessageHeader {
 	versionAndType := iter.readByte()
 	version := versionAndType & protocol.COMPACT_VERSION_MASK
 	messageType := protocol.TMessageType((versionAndType >> 5) & 0x07)
-	if version != protocol.COMPACT_VERSION {
-		iter.ReportError("ReadMessageHeader", fmt.Sprintf("expected version %02x but got %02x", protocol.COMPACT_VERSION, version))
+	switch version {
+	case protocol.COMPACT_VERSION:
+		iter.bigEndianDouble = false
+	case protocol.COMPACT_VERSON_BE:
+		iter.bigEndianDouble = true
+	default:
+		iter.ReportError("ReadMessageHeader", fmt.Sprintf("expected version %02x or %02x but got %02x",
+			protocol.COMPACT_VERSION, protocol.COMPACT_VERSON_BE, version))
 		return protocol.MessageHeader{}
 	}
 	seqId := protocol.SeqId(iter.readVarInt32())
@@ -154,6 +217,20 @@ func (iter *Iterator) ReadMessageHeader() protocol.MessageHeader {
 func (iter *Iterator) ReadStructHeader() {
 	iter.fieldIdStack = append(iter.fieldIdStack, iter.lastFieldId)
 	iter.lastFieldId = 0
+	if iter.maxDepth > 0 && len(iter.fieldIdStack)+iter.containerDepth > iter.maxDepth {
+		iter.ReportError("ReadStructHeader", fmt.Sprintf("struct nested deeper than %d", iter.maxDepth))
+	}
+}
+
+func (iter *Iterator) EnterContainer() {
+	iter.containerDepth++
+	if iter.maxDepth > 0 && len(iter.fieldIdStack)+iter.containerDepth > iter.maxDepth {
+		iter.ReportError("EnterContainer", fmt.Sprintf("container nested deeper than %d", iter.maxDepth))
+	}
+}
+
+func (iter *Iterator) LeaveContainer() {
+	iter.containerDepth--
 }
 
 func (iter *Iterator) ReadStructField() (fieldType protocol.TType, fieldId protocol.FieldId) {
@@ -223,7 +300,9 @@ func (iter *Iterator) ReadBool() bool {
 	if iter.pendingBoolField == 0 {
 		return iter.ReadUint8() == 1
 	}
-	return iter.pendingBoolField == 1
+	val := iter.pendingBoolField == 1
+	iter.pendingBoolField = 0
+	return val
 }
 
 func (iter *Iterator) ReadInt() int {
@@ -272,17 +351,47 @@ func (iter *Iterator) ReadUint64() uint64 {
 
 func (iter *Iterator) ReadFloat64() float64 {
 	tmp := iter.readSmall(8)
+	if iter.bigEndianDouble {
+		return math.Float64frombits(binary.BigEndian.Uint64(tmp))
+	}
 	return math.Float64frombits(binary.LittleEndian.Uint64(tmp))
 }
 
 func (iter *Iterator) ReadString() string {
-	length := iter.readVarInt32()
-	return string(iter.readLarge(int(length)))
+	length := int(iter.readVarInt32())
+	if iter.zeroCopyString {
+		if buf := iter.alias(length); buf != nil {
+			return *(*string)(unsafe.Pointer(&buf))
+		}
+	}
+	return string(iter.readLarge(length))
 }
 
 func (iter *Iterator) ReadBinary() []byte {
-	length := iter.readVarInt32()
+	length := int(iter.readVarInt32())
+	if iter.zeroCopyBinary {
+		if buf := iter.alias(length); buf != nil {
+			return buf
+		}
+	}
 	tmp := make([]byte, length)
-	copy(tmp, iter.readLarge(int(length)))
+	copy(tmp, iter.readLarge(length))
 	return tmp
 }
+
+// SetZeroCopy makes binary, and string if zeroCopyString, decoded from the buffer alias the buffer
+// instead of being copied. Reading from io.Reader or skipping still copies
+func (iter *Iterator) SetZeroCopy(zeroCopyBinary bool, zeroCopyString bool) {
+	iter.zeroCopyBinary = zeroCopyBinary
+	iter.zeroCopyString = zeroCopyString
+}
+
+// alias returns nil if the bytes are not all in the buffer
+func (iter *Iterator) alias(nBytes int) []byte {
+	if nBytes <= 0 || len(iter.preread) < nBytes || iter.skipped != nil {
+		return nil
+	}
+	buf := iter.preread[:nBytes:nBytes]
+	iter.preread = iter.preread[nBytes:]
+	return buf
+}
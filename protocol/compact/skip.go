@@ -17,6 +17,14 @@ func (iter *Iterator) skip(skipper func(), space []byte) []byte {
 }
 
 func (iter *Iterator) Skip(ttype protocol.TType, space []byte) []byte {
+	if ttype == protocol.TypeBool && iter.pendingBoolField != 0 {
+		// the value of bool field has no bytes of its own, it is returned as bool element
+		// so that it can be written back by raw.WriteField
+		if iter.ReadBool() {
+			return append(space, 1)
+		}
+		return append(space, 0)
+	}
 	return iter.skip(func() { iter.Discard(ttype) }, space)
 }
 
@@ -16,6 +16,8 @@ type Stream struct {
 	fieldIdStack     []protocol.FieldId
 	lastFieldId      protocol.FieldId
 	pendingBoolField protocol.FieldId
+	version          byte
+	maxDepth         int
 }
 
 func NewStream(provider spi.ValEncoderProvider, writer io.Writer, buf []byte) *Stream {
@@ -24,13 +26,27 @@ func NewStream(provider spi.ValEncoderProvider, writer io.Writer, buf []byte) *S
 		writer:             writer,
 		buf:                buf,
 		pendingBoolField:   -1,
+		version:            protocol.COMPACT_VERSION,
+		maxDepth:           protocol.DefaultMaxDepth,
 	}
 }
 
+// SetMaxDepth limits the nesting of structs, non-positive means no limit
+func (stream *Stream) SetMaxDepth(maxDepth int) {
+	stream.maxDepth = maxDepth
+}
+
+// SetVersion chooses the version written in message header,
+// protocol.COMPACT_VERSON_BE also makes double encoded as big endian
+func (stream *Stream) SetVersion(version byte) {
+	stream.version = version
+}
+
 func (stream *Stream) Spawn() spi.Stream {
-	return &Stream{
-		ValEncoderProvider: stream.ValEncoderProvider,
-	}
+	spawned := NewStream(stream.ValEncoderProvider, nil, nil)
+	spawned.version = stream.version
+	spawned.maxDepth = stream.maxDepth
+	return spawned
 }
 
 func (stream *Stream) Error() error {
@@ -51,6 +67,9 @@ func (stream *Stream) Reset(writer io.Writer) {
 	stream.writer = writer
 	stream.err = nil
 	stream.buf = stream.buf[:0]
+	stream.fieldIdStack = stream.fieldIdStack[:0]
+	stream.lastFieldId = 0
+	stream.pendingBoolField = -1
 }
 
 func (stream *Stream) Flush() {
@@ -78,7 +97,7 @@ func (stream *Stream) Write(buf []byte) error {
 
 func (stream *Stream) WriteMessageHeader(header protocol.MessageHeader) {
 	stream.buf = append(stream.buf, protocol.COMPACT_PROTOCOL_ID)
-	stream.buf = append(stream.buf, (protocol.COMPACT_VERSION&protocol.COMPACT_VERSION_MASK)|((byte(header.MessageType)<<5)&0x0E0))
+	stream.buf = append(stream.buf, (stream.version&protocol.COMPACT_VERSION_MASK)|((byte(header.MessageType)<<5)&0x0E0))
 	stream.writeVarInt32(int32(header.SeqId))
 	stream.WriteString(header.MessageName)
 }
@@ -95,6 +114,9 @@ func (stream *Stream) WriteListHeader(elemType protocol.TType, length int) {
 func (stream *Stream) WriteStructHeader() {
 	stream.fieldIdStack = append(stream.fieldIdStack, stream.lastFieldId)
 	stream.lastFieldId = 0
+	if stream.maxDepth > 0 && len(stream.fieldIdStack) > stream.maxDepth {
+		stream.ReportError("WriteStructHeader", fmt.Sprintf("struct nested deeper than %d", stream.maxDepth))
+	}
 }
 
 func (stream *Stream) WriteStructField(fieldType protocol.TType, fieldId protocol.FieldId) {
@@ -226,6 +248,19 @@ func (stream *Stream) WriteUint(val uint) {
 
 func (stream *Stream) WriteFloat64(val float64) {
 	bits := math.Float64bits(val)
+	if stream.version == protocol.COMPACT_VERSON_BE {
+		stream.buf = append(stream.buf,
+			byte(bits>>56),
+			byte(bits>>48),
+			byte(bits>>40),
+			byte(bits>>32),
+			byte(bits>>24),
+			byte(bits>>16),
+			byte(bits>>8),
+			byte(bits),
+		)
+		return
+	}
 	stream.buf = append(stream.buf,
 		byte(bits),
 		byte(bits>>8),
@@ -0,0 +1,69 @@
+package protocol
+
+import "fmt"
+
+type ApplicationExceptionType int32
+
+// Application exception types defined by apache thrift
+const (
+	ExceptionUnknown               ApplicationExceptionType = 0
+	ExceptionUnknownMethod         ApplicationExceptionType = 1
+	ExceptionInvalidMessageType    ApplicationExceptionType = 2
+	ExceptionWrongMethodName       ApplicationExceptionType = 3
+	ExceptionBadSequenceId         ApplicationExceptionType = 4
+	ExceptionMissingResult         ApplicationExceptionType = 5
+	ExceptionInternalError         ApplicationExceptionType = 6
+	ExceptionProtocolError         ApplicationExceptionType = 7
+	ExceptionInvalidTransform      ApplicationExceptionType = 8
+	ExceptionInvalidProtocol       ApplicationExceptionType = 9
+	ExceptionUnsupportedClientType ApplicationExceptionType = 10
+)
+
+var exceptionTypeNames = map[ApplicationExceptionType]string{
+	ExceptionUnknown:               "UNKNOWN",
+	ExceptionUnknownMethod:         "UNKNOWN_METHOD",
+	ExceptionInvalidMessageType:    "INVALID_MESSAGE_TYPE",
+	ExceptionWrongMethodName:       "WRONG_METHOD_NAME",
+	ExceptionBadSequenceId:         "BAD_SEQUENCE_ID",
+	ExceptionMissingResult:         "MISSING_RESULT",
+	ExceptionInternalError:         "INTERNAL_ERROR",
+	ExceptionProtocolError:         "PROTOCOL_ERROR",
+	ExceptionInvalidTransform:      "INVALID_TRANSFORM",
+	ExceptionInvalidProtocol:       "INVALID_PROTOCOL",
+	ExceptionUnsupportedClientType: "UNSUPPORTED_CLIENT_TYPE",
+}
+
+func (exceptionType ApplicationExceptionType) String() string {
+	name, found := exceptionTypeNames[exceptionType]
+	if found {
+		return name
+	}
+	return fmt.Sprintf("ApplicationExceptionType(%d)", int32(exceptionType))
+}
+
+// ApplicationException is the body of message with type MessageTypeException,
+// the message is field 1 and the type is field 2
+type ApplicationException struct {
+	Message string
+	Type    ApplicationExceptionType
+}
+
+func NewApplicationException(exceptionType ApplicationExceptionType, message string) *ApplicationException {
+	return &ApplicationException{Message: message, Type: exceptionType}
+}
+
+func (exception *ApplicationException) Error() string {
+	if exception.Message == "" {
+		return "application exception " + exception.Type.String()
+	}
+	return "application exception " + exception.Type.String() + ": " + exception.Message
+}
+
+// ExceptionHeader is the header of exception replied to the request
+func ExceptionHeader(request MessageHeader) MessageHeader {
+	return MessageHeader{
+		MessageName: request.MessageName,
+		MessageType: MessageTypeException,
+		SeqId:       request.SeqId,
+	}
+}
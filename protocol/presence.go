@@ -0,0 +1,54 @@
+package protocol
+
+// Presence records which fields of a struct are set.
+// Embed it into the struct to tell absent field from field holding zero value,
+// decoder marks the fields read, encoder writes exactly the fields marked, zero or not
+type Presence struct {
+	bits         []uint64
+	negativeBits []uint64
+}
+
+func (presence Presence) IsSet(fieldId FieldId) bool {
+	bits, word, mask := presence.bitOf(fieldId)
+	if word >= len(*bits) {
+		return false
+	}
+	return (*bits)[word]&mask != 0
+}
+
+func (presence *Presence) Set(fieldId FieldId) {
+	bits, word, mask := presence.bitOf(fieldId)
+	for word >= len(*bits) {
+		*bits = append(*bits, 0)
+	}
+	(*bits)[word] |= mask
+}
+
+func (presence *Presence) Unset(fieldId FieldId) {
+	bits, word, mask := presence.bitOf(fieldId)
+	if word >= len(*bits) {
+		return
+	}
+	(*bits)[word] &^= mask
+}
+
+// Reset marks all fields as absent
+func (presence *Presence) Reset() {
+	for i := range presence.bits {
+		presence.bits[i] = 0
+	}
+	for i := range presence.negativeBits {
+		presence.negativeBits[i] = 0
+	}
+}
+
+// bitOf locates the bit of field, negative field ids (assigned by old idl compilers
+// to fields without explicit id) count down from -1 in their own words
+func (presence *Presence) bitOf(fieldId FieldId) (*[]uint64, int, uint64) {
+	if fieldId < 0 {
+		index := -(int(fieldId) + 1)
+		return &presence.negativeBits, index / 64, 1 << uint(index%64)
+	}
+	index := int(fieldId)
+	return &presence.bits, index / 64, 1 << uint(index%64)
+}
@@ -18,6 +18,9 @@ const (
 	COMPACT_TYPE_SHIFT_AMOUT = 5
 )
 
+// DefaultMaxDepth limits the nesting of structs read or written, same as the default recursion depth of apache thrift
+const DefaultMaxDepth = 64
+
 const (
 	MessgeTypeInvalid    TMessageType = 0
 	MessageTypeCall      TMessageType = 1
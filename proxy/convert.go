@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+)
+
+// encodeArgs writes args in the field order of body, which is the arguments read by from protocol.
+// The fields kept by Rewrite are written as they are, the fields added follow in field id order
+func (proxy *Proxy) encodeArgs(from thrifter.Protocol, to thrifter.Protocol, body []byte, args raw.Struct) ([]byte, error) {
+	fromAPI := proxy.apiOf(from)
+	iter := fromAPI.NewIterator(nil, body)
+	stream := fromAPI.NewStream(nil, nil)
+	written := make(map[protocol.FieldId]bool, len(args))
+	iter.ReadStructHeader()
+	stream.WriteStructHeader()
+	for iter.Error() == nil {
+		fieldType, fieldId := iter.ReadStructField()
+		if fieldType == protocol.TypeStop {
+			break
+		}
+		iter.Discard(fieldType)
+		if field, found := args[fieldId]; found {
+			raw.WriteField(stream, field.Type, fieldId, field.Buffer)
+			written[fieldId] = true
+		}
+	}
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+	added := raw.Struct{}
+	for fieldId, field := range args {
+		if !written[fieldId] {
+			added[fieldId] = field
+		}
+	}
+	added.WriteFields(stream)
+	stream.WriteStructFieldStop()
+	if stream.Error() != nil {
+		return nil, stream.Error()
+	}
+	return proxy.convert(from, to, stream.Buffer())
+}
+
+// convert makes the struct read by one protocol writable by another protocol,
+// the values are transcoded one by one keeping their exact types and order
+func (proxy *Proxy) convert(from thrifter.Protocol, to thrifter.Protocol, body []byte) ([]byte, error) {
+	if from == to {
+		return body, nil
+	}
+	stream := proxy.apiOf(to).NewStream(nil, nil)
+	if err := thrifter.TranscodeValue(proxy.apiOf(from).NewIterator(nil, body), stream, protocol.TypeStruct); err != nil {
+		return nil, err
+	}
+	return stream.Buffer(), nil
+}
@@ -0,0 +1,363 @@
+// Package proxy forwards thrift messages to upstreams chosen by message name, without IDL.
+// The arguments are kept as raw.Struct, so that a hook can rewrite some fields and leave others untouched,
+// the fields are forwarded in the order they are received
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/middleware"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/transport"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrProxyClosed is returned by Serve after Close
+var ErrProxyClosed = errors.New("thrifter: proxy closed")
+
+// Upstream is where the calls are forwarded. The connections to upstream are reused by
+// Protocol, Framed and Addr, so Route can return a new *Upstream for each call
+type Upstream struct {
+	Protocol thrifter.Protocol
+	Framed   bool
+	// Addr is dialed with tcp if Dial is not set. Set it with Dial as well
+	// to tell upstreams apart, as they share the connection otherwise
+	Addr string
+	Dial func(ctx context.Context) (net.Conn, error)
+}
+
+// upstreamKey identifies the upstream connection of proxyConn to reuse
+type upstreamKey struct {
+	protocol thrifter.Protocol
+	framed   bool
+	addr     string
+}
+
+func (upstream *Upstream) key() upstreamKey {
+	key := upstreamKey{protocol: upstream.Protocol, framed: upstream.Framed, addr: upstream.Addr}
+	if key.protocol == 0 {
+		key.protocol = thrifter.ProtocolBinary
+	}
+	return key
+}
+
+type Config struct {
+	// Protocol and Framed are used by the connections accepted
+	Protocol thrifter.Protocol
+	Framed   bool
+	// Route chooses the upstream of message, returning *protocol.ApplicationException
+	// replies it to the caller, other error is replied as INTERNAL_ERROR
+	Route func(messageName string) (*Upstream, error)
+	// Rewrite is optional, it can modify the header and arguments before forwarding
+	Rewrite func(header *protocol.MessageHeader, args raw.Struct) error
+	// Interceptors process the calls before routing, the args of call is raw.Struct, the result is opaque
+	Interceptors []middleware.Interceptor
+	// DialTimeout limits the time connecting upstream, 5 seconds if not set
+	DialTimeout time.Duration
+	// CallTimeout limits the time of each call forwarded to upstream, including the time connecting it.
+	// The call not replied in time is replied as INTERNAL_ERROR, no limit if not set
+	CallTimeout time.Duration
+}
+
+type Proxy struct {
+	cfg       Config
+	api       thrifter.API
+	apis      sync.Map
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*proxyConn]struct{}
+	closed    bool
+	// ctx is cancelled by Close to interrupt the calls being forwarded
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func New(cfg Config) *Proxy {
+	if cfg.Protocol == 0 {
+		cfg.Protocol = thrifter.ProtocolBinary
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	proxy := &Proxy{
+		cfg:       cfg,
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[*proxyConn]struct{}{},
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	proxy.api = proxy.apiOf(cfg.Protocol)
+	return proxy
+}
+
+func (proxy *Proxy) apiOf(protocol thrifter.Protocol) thrifter.API {
+	api, found := proxy.apis.Load(protocol)
+	if found {
+		return api.(thrifter.API)
+	}
+	api, _ = proxy.apis.LoadOrStore(protocol, thrifter.Config{Protocol: protocol}.Froze())
+	return api.(thrifter.API)
+}
+
+// Serve accepts connections from listener until the proxy is closed
+func (proxy *Proxy) Serve(listener net.Listener) error {
+	proxy.mu.Lock()
+	if proxy.closed {
+		proxy.mu.Unlock()
+		listener.Close()
+		return ErrProxyClosed
+	}
+	proxy.listeners[listener] = struct{}{}
+	proxy.mu.Unlock()
+	defer func() {
+		proxy.mu.Lock()
+		delete(proxy.listeners, listener)
+		proxy.mu.Unlock()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			proxy.mu.Lock()
+			closed := proxy.closed
+			proxy.mu.Unlock()
+			if closed {
+				return ErrProxyClosed
+			}
+			return err
+		}
+		go proxy.ServeConn(conn)
+	}
+}
+
+// ServeConn forwards messages from one connection until it is closed
+func (proxy *Proxy) ServeConn(conn io.ReadWriteCloser) {
+	pc := &proxyConn{
+		proxy:     proxy,
+		conn:      transport.NewConn(proxy.api, conn, proxy.cfg.Framed),
+		upstreams: map[upstreamKey]*upstreamConn{},
+	}
+	proxy.mu.Lock()
+	if proxy.closed {
+		proxy.mu.Unlock()
+		conn.Close()
+		return
+	}
+	proxy.conns[pc] = struct{}{}
+	proxy.mu.Unlock()
+	defer func() {
+		proxy.mu.Lock()
+		delete(proxy.conns, pc)
+		proxy.mu.Unlock()
+	}()
+	pc.serve()
+}
+
+// Close closes all listeners and connections
+func (proxy *Proxy) Close() error {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	proxy.closed = true
+	proxy.cancel()
+	for listener := range proxy.listeners {
+		listener.Close()
+	}
+	for pc := range proxy.conns {
+		pc.conn.Close()
+	}
+	return nil
+}
+
+type proxyConn struct {
+	proxy     *Proxy
+	conn      *transport.Conn
+	upstreams map[upstreamKey]*upstreamConn
+}
+
+type upstreamConn struct {
+	protocol thrifter.Protocol
+	netConn  net.Conn
+	conn     *transport.Conn
+	seqId    protocol.SeqId
+}
+
+func (pc *proxyConn) serve() {
+	defer pc.close()
+	for {
+		header, err := pc.conn.ReadMessageHeader()
+		if err != nil {
+			return
+		}
+		body, err := pc.conn.SkipMessageBody()
+		if err != nil {
+			return
+		}
+		var args raw.Struct
+		if err := pc.proxy.api.Unmarshal(body, &args); err != nil {
+			return
+		}
+		if !pc.forward(header, body, args) {
+			return
+		}
+	}
+}
+
+func (pc *proxyConn) close() {
+	pc.conn.Close()
+	for _, upstream := range pc.upstreams {
+		upstream.conn.Close()
+	}
+}
+
+// forward returns false if the connection accepted can not be used any more
+func (pc *proxyConn) forward(header protocol.MessageHeader, body []byte, args raw.Struct) bool {
+	proxy := pc.proxy
+	ctx := proxy.ctx
+	if proxy.cfg.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, proxy.cfg.CallTimeout)
+		defer cancel()
+	}
+	handler := func(ctx context.Context, call *middleware.Call) (interface{}, error) {
+		return pc.forwardCall(ctx, call, body)
+	}
+	call := middleware.NewCall(proxy.api, header, args)
+	result, err := middleware.Wrap(handler, proxy.cfg.Interceptors...)(ctx, call)
+	if header.MessageType == protocol.MessageTypeOneWay {
+		return true
+	}
+	if err != nil {
+		exception, isException := err.(*protocol.ApplicationException)
+		if !isException {
+			exception = protocol.NewApplicationException(protocol.ExceptionInternalError, err.Error())
+		}
+		return pc.conn.WriteException(header, exception) == nil
+	}
+	upstreamReply, isReply := result.(*reply)
+	if !isReply {
+		return pc.conn.WriteException(header, protocol.NewApplicationException(
+			protocol.ExceptionMissingResult, "interceptor returned no reply")) == nil
+	}
+	upstreamReply.header.SeqId = header.SeqId
+	return pc.conn.WriteEncodedMessage(upstreamReply.header, upstreamReply.body) == nil
+}
+
+// forwardCall returns the reply of upstream as *reply, body is the arguments as received
+func (pc *proxyConn) forwardCall(ctx context.Context, call *middleware.Call, body []byte) (interface{}, error) {
+	proxy := pc.proxy
+	args, err := call.RawArgs()
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := proxy.cfg.Route(call.Header.MessageName)
+	if err != nil {
+		return nil, err
+	}
+	if upstream == nil {
+		return nil, protocol.NewApplicationException(protocol.ExceptionUnknownMethod,
+			"unknown method "+call.Header.MessageName)
+	}
+	if proxy.cfg.Rewrite != nil {
+		if err := proxy.cfg.Rewrite(&call.Header, args); err != nil {
+			return nil, err
+		}
+	}
+	uc, err := pc.upstreamOf(ctx, upstream)
+	if err != nil {
+		return nil, err
+	}
+	body, err = proxy.encodeArgs(proxy.cfg.Protocol, uc.protocol, body, args)
+	if err != nil {
+		return nil, err
+	}
+	upstreamReply, err := uc.roundTrip(ctx, call.Header, body)
+	if err != nil {
+		delete(pc.upstreams, upstream.key())
+		uc.conn.Close()
+		return nil, err
+	}
+	if call.Header.MessageType == protocol.MessageTypeOneWay {
+		return nil, nil
+	}
+	upstreamReply.body, err = proxy.convert(uc.protocol, proxy.cfg.Protocol, upstreamReply.body)
+	if err != nil {
+		return nil, err
+	}
+	return upstreamReply, nil
+}
+
+func (pc *proxyConn) upstreamOf(ctx context.Context, upstream *Upstream) (*upstreamConn, error) {
+	key := upstream.key()
+	uc := pc.upstreams[key]
+	if uc != nil {
+		return uc, nil
+	}
+	dial := upstream.Dial
+	if dial == nil {
+		dial = func(ctx context.Context) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "tcp", upstream.Addr)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, pc.proxy.cfg.DialTimeout)
+	defer cancel()
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uc = &upstreamConn{
+		protocol: key.protocol,
+		netConn:  conn,
+		conn:     transport.NewConn(pc.proxy.apiOf(key.protocol), conn, key.framed),
+	}
+	pc.upstreams[key] = uc
+	return uc, nil
+}
+
+type reply struct {
+	header protocol.MessageHeader
+	// body is encoded by the protocol of upstream, converted before replying
+	body []byte
+}
+
+// roundTrip sends the call with seqid of the upstream connection, and reads the reply.
+// The connection can not be used any more if error is returned
+func (uc *upstreamConn) roundTrip(ctx context.Context, header protocol.MessageHeader, body []byte) (upstreamReply *reply, err error) {
+	stop, err := transport.WatchContext(ctx, uc.netConn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		stop()
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}()
+	uc.seqId++
+	header.SeqId = uc.seqId
+	if err := uc.conn.WriteEncodedMessage(header, body); err != nil {
+		return nil, err
+	}
+	if header.MessageType == protocol.MessageTypeOneWay {
+		return nil, nil
+	}
+	replyHeader, err := uc.conn.ReadMessageHeader()
+	if err != nil {
+		return nil, err
+	}
+	if replyHeader.SeqId != uc.seqId {
+		return nil, protocol.NewApplicationException(protocol.ExceptionBadSequenceId,
+			fmt.Sprintf("upstream replied seqid %d to %d", replyHeader.SeqId, uc.seqId))
+	}
+	replyBody, err := uc.conn.SkipMessageBody()
+	if err != nil {
+		return nil, err
+	}
+	return &reply{header: replyHeader, body: replyBody}, nil
+}
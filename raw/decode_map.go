@@ -44,11 +44,18 @@ func readerOf(valType protocol.TType) func([]byte, spi.Iterator) interface{} {
 		return readFloat64
 	case protocol.TypeString:
 		return readString
+	case protocol.TypeList, protocol.TypeSet, protocol.TypeMap, protocol.TypeStruct:
+		return readEncoded
 	default:
 		panic("unsupported type")
 	}
 }
 
+// readEncoded keys the complex values by their bytes, which are not hashable once decoded
+func readEncoded(buf []byte, iter spi.Iterator) interface{} {
+	return string(buf)
+}
+
 func readBool(buf []byte, iter spi.Iterator) interface{} {
 	iter.Reset(nil, buf)
 	return iter.ReadBool()
@@ -11,10 +11,7 @@ type rawStructEncoder struct {
 func (encoder *rawStructEncoder) Encode(val interface{}, stream spi.Stream) {
 	obj := val.(Struct)
 	stream.WriteStructHeader()
-	for fieldId, field := range obj {
-		stream.WriteStructField(field.Type, fieldId)
-		stream.Write(field.Buffer)
-	}
+	obj.WriteFields(stream)
 	stream.WriteStructFieldStop()
 }
 
@@ -0,0 +1,66 @@
+package raw
+
+import (
+	"bytes"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"sort"
+)
+
+// Equal tells if both structs have same fields of same types and bytes.
+// Values encoded differently are not equal, such as maps written in different order
+func Equal(a Struct, b Struct) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for fieldId, fieldA := range a {
+		fieldB, found := b[fieldId]
+		if !found || !fieldEqual(fieldA, fieldB) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone copies the struct with its buffers, so that the copy does not alias the decoded input
+func Clone(obj Struct) Struct {
+	if obj == nil {
+		return nil
+	}
+	cloned := make(Struct, len(obj))
+	for fieldId, field := range obj {
+		cloned[fieldId] = StructField{Type: field.Type, Buffer: append([]byte{}, field.Buffer...)}
+	}
+	return cloned
+}
+
+// DiffFields compares the fields without decoding them, the ids returned are in ascending order.
+// Changed fields differ in type or bytes, they might still be equal values if encoded differently
+func DiffFields(old Struct, new Struct) (added []protocol.FieldId, removed []protocol.FieldId, changed []protocol.FieldId) {
+	for fieldId, oldField := range old {
+		newField, found := new[fieldId]
+		if !found {
+			removed = append(removed, fieldId)
+		} else if !fieldEqual(oldField, newField) {
+			changed = append(changed, fieldId)
+		}
+	}
+	for fieldId := range new {
+		if _, found := old[fieldId]; !found {
+			added = append(added, fieldId)
+		}
+	}
+	sortFieldIds(added)
+	sortFieldIds(removed)
+	sortFieldIds(changed)
+	return
+}
+
+func fieldEqual(a StructField, b StructField) bool {
+	return a.Type == b.Type && bytes.Equal(a.Buffer, b.Buffer)
+}
+
+func sortFieldIds(fieldIds []protocol.FieldId) {
+	sort.Slice(fieldIds, func(i, j int) bool {
+		return fieldIds[i] < fieldIds[j]
+	})
+}
@@ -0,0 +1,58 @@
+package raw
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+)
+
+// ReadField keeps the field value read from iter as bytes,
+// used to preserve fields a go struct does not declare
+func (obj *Struct) ReadField(iter spi.Iterator, fieldType protocol.TType, fieldId protocol.FieldId) {
+	if *obj == nil {
+		*obj = Struct{}
+	}
+	(*obj)[fieldId] = StructField{
+		Type:   fieldType,
+		Buffer: iter.Skip(fieldType, nil),
+	}
+}
+
+// WriteFields writes the fields into a struct being encoded in field id order, except the ones in excludes
+func (obj Struct) WriteFields(stream spi.Stream, excludes ...protocol.FieldId) {
+	for _, fieldId := range obj.fieldIds() {
+		if isExcluded(fieldId, excludes) {
+			continue
+		}
+		field := obj[fieldId]
+		WriteField(stream, field.Type, fieldId, field.Buffer)
+	}
+}
+
+// WriteField writes a struct field whose value is already encoded in buf.
+// The bool field is written by its value, as compact protocol keeps it in the field header
+func WriteField(stream spi.Stream, fieldType protocol.TType, fieldId protocol.FieldId, buf []byte) {
+	stream.WriteStructField(fieldType, fieldId)
+	if fieldType == protocol.TypeBool {
+		stream.WriteBool(len(buf) == 1 && buf[0] == 1)
+		return
+	}
+	stream.Write(buf)
+}
+
+func (obj Struct) fieldIds() []protocol.FieldId {
+	fieldIds := make([]protocol.FieldId, 0, len(obj))
+	for fieldId := range obj {
+		fieldIds = append(fieldIds, fieldId)
+	}
+	sortFieldIds(fieldIds)
+	return fieldIds
+}
+
+func isExcluded(fieldId protocol.FieldId, excludes []protocol.FieldId) bool {
+	for _, exclude := range excludes {
+		if exclude == fieldId {
+			return true
+		}
+	}
+	return false
+}
@@ -22,5 +22,7 @@ type MapEntry struct {
 type Map struct {
 	KeyType     protocol.TType
 	ElementType protocol.TType
-	Entries     map[interface{}]MapEntry
+	// Entries are keyed by the decoded key, or by the encoded key as string
+	// if the key type is list, set, map or struct
+	Entries map[interface{}]MapEntry
 }
\ No newline at end of file
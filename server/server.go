@@ -0,0 +1,380 @@
+// Package server serves thrift calls by decoding the arguments into registered go structs,
+// like apache thrift TSimpleServer with generated processors, but without generated code
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/middleware"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/transport"
+	"io"
+	"log"
+	"net"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve after Shutdown or Close
+var ErrServerClosed = errors.New("thrifter: server closed")
+
+// HandlerFunc serves a call. args is a pointer to a new value of the argument struct registered with the method.
+// The result is encoded as the reply, usually a struct having the return value as field 0 and the
+// declared exceptions as other fields. Returning error replies an application exception instead,
+// error other than *protocol.ApplicationException is replied as INTERNAL_ERROR.
+type HandlerFunc func(ctx context.Context, args interface{}) (result interface{}, err error)
+
+type Config struct {
+	// API decides the protocol, thrifter.DefaultConfig if nil
+	API    thrifter.API
+	Framed bool
+	// Interceptors process the calls before handlers, the args of call is the bound struct,
+	// or raw.Struct if the method is unknown
+	Interceptors []middleware.Interceptor
+	// ErrorLog logs the panics of handlers, log.Default() if nil
+	ErrorLog *log.Logger
+}
+
+type method struct {
+	argsType reflect.Type
+	handler  HandlerFunc
+	oneway   bool
+}
+
+type Server struct {
+	api          thrifter.API
+	framed       bool
+	interceptors []middleware.Interceptor
+	errorLog     *log.Logger
+	methodsMu    sync.RWMutex
+	methods      map[string]*method
+	mu           sync.Mutex
+	listeners    map[net.Listener]struct{}
+	conns        map[*serverConn]struct{}
+	inShutdown   bool
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+func New(cfg Config) *Server {
+	api := cfg.API
+	if api == nil {
+		api = thrifter.DefaultConfig
+	}
+	errorLog := cfg.ErrorLog
+	if errorLog == nil {
+		errorLog = log.Default()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		api:          api,
+		framed:       cfg.Framed,
+		interceptors: cfg.Interceptors,
+		errorLog:     errorLog,
+		methods:      map[string]*method{},
+		listeners:    map[net.Listener]struct{}{},
+		conns:        map[*serverConn]struct{}{},
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Handle registers the handler of method, args is a sample of the argument struct, such as Args{} or (*Args)(nil)
+func (srv *Server) Handle(methodName string, args interface{}, handler HandlerFunc) {
+	srv.handle(methodName, args, handler, false)
+}
+
+// HandleOneway registers the handler of oneway method, the result of handler is not replied.
+// The method called with message type call is replied INVALID_MESSAGE_TYPE, without running the handler
+func (srv *Server) HandleOneway(methodName string, args interface{}, handler HandlerFunc) {
+	srv.handle(methodName, args, handler, true)
+}
+
+func (srv *Server) handle(methodName string, args interface{}, handler HandlerFunc, oneway bool) {
+	argsType := reflect.TypeOf(args)
+	if argsType == nil {
+		panic("args sample of " + methodName + " is nil")
+	}
+	if argsType.Kind() == reflect.Ptr {
+		argsType = argsType.Elem()
+	}
+	srv.methodsMu.Lock()
+	defer srv.methodsMu.Unlock()
+	srv.methods[methodName] = &method{argsType: argsType, handler: handler, oneway: oneway}
+}
+
+func (srv *Server) methodOf(methodName string) *method {
+	srv.methodsMu.RLock()
+	defer srv.methodsMu.RUnlock()
+	return srv.methods[methodName]
+}
+
+// Serve accepts connections from listener until the server is shutdown, the listener is closed on return
+func (srv *Server) Serve(listener net.Listener) error {
+	if !srv.trackListener(listener, true) {
+		listener.Close()
+		return ErrServerClosed
+	}
+	defer srv.trackListener(listener, false)
+	var tempDelay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if srv.shuttingDown() {
+				return ErrServerClosed
+			}
+			if isTemporary(err) {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else if tempDelay *= 2; tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+		go srv.ServeConn(conn)
+	}
+}
+
+// isTemporary tells if accepting again might succeed, such as running out of file descriptors for a while
+func isTemporary(err error) bool {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// ServeConn serves calls from one connection until it is closed or the server is shutdown
+func (srv *Server) ServeConn(conn io.ReadWriteCloser) {
+	sc := &serverConn{srv: srv, conn: transport.NewConn(srv.api, conn, srv.framed)}
+	if !srv.trackConn(sc, true) {
+		conn.Close()
+		return
+	}
+	defer srv.trackConn(sc, false)
+	defer sc.conn.Close()
+	sc.serve()
+}
+
+// Shutdown stops accepting connections, closes idle connections, then waits
+// the calls being served to finish. It returns ctx.Err() if ctx is done before that
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.inShutdown = true
+	for listener := range srv.listeners {
+		listener.Close()
+	}
+	srv.mu.Unlock()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if srv.closeIdleConns() {
+			srv.cancel()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes all listeners and connections immediately, the context of calls being served is cancelled
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.inShutdown = true
+	srv.cancel()
+	var err error
+	for listener := range srv.listeners {
+		if closeErr := listener.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	for sc := range srv.conns {
+		sc.conn.Close()
+	}
+	return err
+}
+
+func (srv *Server) shuttingDown() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.inShutdown
+}
+
+func (srv *Server) trackListener(listener net.Listener, add bool) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if add {
+		if srv.inShutdown {
+			return false
+		}
+		srv.listeners[listener] = struct{}{}
+	} else {
+		delete(srv.listeners, listener)
+	}
+	return true
+}
+
+func (srv *Server) trackConn(sc *serverConn, add bool) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if add {
+		if srv.inShutdown {
+			return false
+		}
+		srv.conns[sc] = struct{}{}
+	} else {
+		delete(srv.conns, sc)
+	}
+	return true
+}
+
+// closeIdleConns tells if all connections are closed
+func (srv *Server) closeIdleConns() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for sc := range srv.conns {
+		sc.mu.Lock()
+		if !sc.active {
+			sc.conn.Close()
+		}
+		sc.mu.Unlock()
+	}
+	return len(srv.conns) == 0
+}
+
+type serverConn struct {
+	srv    *Server
+	conn   *transport.Conn
+	mu     sync.Mutex
+	active bool
+}
+
+func (sc *serverConn) serve() {
+	for {
+		var header protocol.MessageHeader
+		err := sc.read(func() (err error) {
+			header, err = sc.conn.ReadMessageHeader()
+			return err
+		})
+		if err != nil {
+			return
+		}
+		if !sc.setActive(true) {
+			return
+		}
+		if !sc.serveCall(header) {
+			return
+		}
+		if !sc.setActive(false) {
+			return
+		}
+	}
+}
+
+// read turns the panic of decoder on malformed input into error
+func (sc *serverConn) read(decode func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("malformed message: %v", recovered)
+		}
+	}()
+	return decode()
+}
+
+// setActive returns false if the server is shutting down
+func (sc *serverConn) setActive(active bool) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.active = active
+	return !sc.srv.shuttingDown()
+}
+
+// serveCall returns false if the connection can not be used any more
+func (sc *serverConn) serveCall(header protocol.MessageHeader) bool {
+	if header.MessageType != protocol.MessageTypeCall && header.MessageType != protocol.MessageTypeOneWay {
+		sc.conn.WriteException(header, protocol.NewApplicationException(protocol.ExceptionInvalidMessageType,
+			fmt.Sprintf("unexpected message type %d", header.MessageType)))
+		return false
+	}
+	method := sc.srv.methodOf(header.MessageName)
+	var args interface{}
+	var handler middleware.Handler
+	oneway := header.MessageType == protocol.MessageTypeOneWay
+	if method == nil {
+		var rawArgs raw.Struct
+		if err := sc.read(func() error { return sc.conn.ReadMessageBody(&rawArgs) }); err != nil {
+			return false
+		}
+		args = rawArgs
+		handler = func(ctx context.Context, call *middleware.Call) (interface{}, error) {
+			return nil, protocol.NewApplicationException(
+				protocol.ExceptionUnknownMethod, "unknown method "+call.Header.MessageName)
+		}
+	} else {
+		args = reflect.New(method.argsType).Interface()
+		if err := sc.read(func() error { return sc.conn.ReadMessageBody(args) }); err != nil {
+			if !oneway {
+				sc.conn.WriteException(header, protocol.NewApplicationException(
+					protocol.ExceptionProtocolError, err.Error()))
+			}
+			return false
+		}
+		if method.oneway && !oneway {
+			// the caller waits for the reply, which the oneway handler does not have
+			return sc.conn.WriteException(header, protocol.NewApplicationException(
+				protocol.ExceptionInvalidMessageType, "oneway method "+header.MessageName+" called as call")) == nil
+		}
+		handler = func(ctx context.Context, call *middleware.Call) (interface{}, error) {
+			return method.handler(ctx, call.Args())
+		}
+	}
+	call := middleware.NewCall(sc.srv.api, header, args)
+	result, err := sc.invoke(middleware.Wrap(handler, sc.srv.interceptors...), call)
+	if oneway {
+		return true
+	}
+	if err != nil {
+		exception, isException := err.(*protocol.ApplicationException)
+		if !isException {
+			exception = protocol.NewApplicationException(protocol.ExceptionInternalError, err.Error())
+		}
+		return sc.conn.WriteException(header, exception) == nil
+	}
+	if result == nil {
+		result = general.Struct{}
+	}
+	return sc.conn.WriteMessage(protocol.MessageHeader{
+		MessageName: header.MessageName,
+		MessageType: protocol.MessageTypeReply,
+		SeqId:       header.SeqId,
+	}, result) == nil
+}
+
+// invoke logs the panic of handler and replies it as INTERNAL_ERROR, the connection is kept
+func (sc *serverConn) invoke(handler middleware.Handler, call *middleware.Call) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			sc.srv.errorLog.Printf("thrift call %s seqid %d panic: %v\n%s",
+				call.Header.MessageName, call.Header.SeqId, recovered, debug.Stack())
+			result = nil
+			err = protocol.NewApplicationException(protocol.ExceptionInternalError,
+				fmt.Sprintf("panic: %v", recovered))
+		}
+	}()
+	return handler(sc.srv.ctx, call)
+}
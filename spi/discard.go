@@ -1,10 +1,12 @@
 package spi
 
 func DiscardList(iter Iterator) {
+	EnterContainer(iter)
 	elemType, size := iter.ReadListHeader()
 	for i := 0; i < size; i++ {
 		iter.Discard(elemType)
 	}
+	LeaveContainer(iter)
 }
 
 func DiscardStruct(iter Iterator) {
@@ -19,9 +21,11 @@ func DiscardStruct(iter Iterator) {
 }
 
 func DiscardMap(iter Iterator) {
+	EnterContainer(iter)
 	keyType, elemType, size := iter.ReadMapHeader()
 	for i := 0; i < size; i++ {
 		iter.Discard(keyType)
 		iter.Discard(elemType)
 	}
+	LeaveContainer(iter)
 }
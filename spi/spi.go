@@ -4,6 +4,7 @@ import (
 	"io"
 	"github.com/batchcorp/thrift-iterator/protocol"
 	"reflect"
+	"strings"
 )
 
 type Iterator interface {
@@ -40,6 +41,30 @@ type Iterator interface {
 	Discard(ttype protocol.TType)
 }
 
+// ContainerCounter is implemented by the iterators limiting the nesting depth, it is optional so that
+// Iterator implemented outside keeps working. EnterContainer and LeaveContainer count the list, set or map
+// being read towards the limit, which counts the structs by ReadStructHeader and its field stop.
+// They are called by the readers following the input, such as Discard, the readers following go types
+// do not nest without structs
+type ContainerCounter interface {
+	EnterContainer()
+	LeaveContainer()
+}
+
+// EnterContainer counts the container being read, if iter is a ContainerCounter
+func EnterContainer(iter Iterator) {
+	if counter, ok := iter.(ContainerCounter); ok {
+		counter.EnterContainer()
+	}
+}
+
+// LeaveContainer ends the container counted by EnterContainer
+func LeaveContainer(iter Iterator) {
+	if counter, ok := iter.(ContainerCounter); ok {
+		counter.LeaveContainer()
+	}
+}
+
 type Stream interface {
 	ValEncoderProvider
 	Spawn() Stream
@@ -82,11 +107,13 @@ type ValDecoder interface {
 
 type ValDecoderProvider interface {
 	PrepareDecoder(valType reflect.Type)
+	PrepareFieldDecoder(valType reflect.Type, options ...string)
 	GetDecoder(decoderName string) ValDecoder
 }
 
 type ValEncoderProvider interface {
 	PrepareEncoder(valType reflect.Type)
+	PrepareFieldEncoder(valType reflect.Type, options ...string)
 	GetEncoder(encoderName string) ValEncoder
 }
 
@@ -95,6 +122,28 @@ type Extension interface {
 	EncoderOf(valType reflect.Type) ValEncoder
 }
 
+// FieldExtension is optionally implemented by extensions whose encoding depends on
+// the options following the field id in the struct tag, like `thrift:"created,1,rfc3339"`
+type FieldExtension interface {
+	FieldDecoderOf(valType reflect.Type, options []string) ValDecoder
+	FieldEncoderOf(valType reflect.Type, options []string) ValEncoder
+}
+
+// FieldCodecName is the name used to look up the decoder/encoder prepared for valType with tag options
+func FieldCodecName(valType reflect.Type, options []string) string {
+	return valType.String() + "," + strings.Join(options, ",")
+}
+
+// HasOption tells if option is one of the struct tag options following the field name and id
+func HasOption(options []string, option string) bool {
+	for _, candidate := range options {
+		if candidate == option {
+			return true
+		}
+	}
+	return false
+}
+
 type DummyExtension struct {
 }
 
@@ -126,4 +175,57 @@ func (extensions Extensions) EncoderOf(valType reflect.Type) ValEncoder {
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (extensions Extensions) FieldDecoderOf(valType reflect.Type, options []string) ValDecoder {
+	for _, extension := range extensions {
+		fieldExtension, isFieldExtension := extension.(FieldExtension)
+		if !isFieldExtension {
+			continue
+		}
+		decoder := fieldExtension.FieldDecoderOf(valType, options)
+		if decoder != nil {
+			return decoder
+		}
+	}
+	return nil
+}
+
+func (extensions Extensions) FieldEncoderOf(valType reflect.Type, options []string) ValEncoder {
+	for _, extension := range extensions {
+		fieldExtension, isFieldExtension := extension.(FieldExtension)
+		if !isFieldExtension {
+			continue
+		}
+		encoder := fieldExtension.FieldEncoderOf(valType, options)
+		if encoder != nil {
+			return encoder
+		}
+	}
+	return nil
+}
+
+// StringPolicy decides how general objects decode TypeString, which is used for both string and binary
+type StringPolicy int
+
+const (
+	// StringAlways decodes as string
+	StringAlways StringPolicy = iota
+	// BinaryAlways decodes as []byte
+	BinaryAlways
+	// BinaryIfInvalidUTF8 decodes as string if the bytes are valid utf8, otherwise as []byte
+	BinaryIfInvalidUTF8
+)
+
+// OmitPolicy decides which struct fields are left out when encoding
+type OmitPolicy int
+
+const (
+	// OmitNil skips nil pointer, slice, map and interface fields
+	OmitNil OmitPolicy = iota
+	// OmitZero skips empty fields as well, like tagging every field with omitempty
+	OmitZero
+	// OmitNever always writes the field, nil slice and map as empty.
+	// nil pointer and interface are still skipped, as there is no value to write.
+	OmitNever
+)
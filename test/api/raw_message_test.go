@@ -14,9 +14,9 @@ func Test_decode_struct_of_raw_message(t *testing.T) {
 	should := require.New(t)
 	api := thrifter.Config{Protocol: thrifter.ProtocolBinary, StaticCodegen: false}.Froze()
 	output, err := api.Marshal(general.Struct{
-		0: general.Map{
+		0: general.Map{Entries: map[interface{}]interface{}{
 			"key1": "value1",
-		},
+		}},
 		1: "hello",
 	})
 	should.Nil(err)
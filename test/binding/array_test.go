@@ -0,0 +1,108 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Point struct {
+	X int32 `thrift:"x,1"`
+	Y int32 `thrift:"y,2"`
+}
+
+type Fingerprint struct {
+	Hash    [4]byte              `thrift:"hash,1"`
+	Vector  [3]int32             `thrift:"vector,2"`
+	Corners [2]Point             `thrift:"corners,3"`
+	Grid    [2][2]int64          `thrift:"grid,4"`
+	Tags    [2]map[string]string `thrift:"tags,5"`
+}
+
+func Test_encode_array(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(Fingerprint{
+			Hash:    [4]byte{1, 2, 3, 4},
+			Vector:  [3]int32{5, 6, 7},
+			Corners: [2]Point{{1, 2}, {3, 4}},
+			Grid:    [2][2]int64{{1, 2}, {3, 4}},
+			Tags:    [2]map[string]string{{"k": "v"}, nil},
+		})
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal("\x01\x02\x03\x04", val[protocol.FieldId(1)])
+		should.Equal(general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(5), int32(6), int32(7)}},
+			val[protocol.FieldId(2)])
+		should.Equal(int32(3), val.Get(protocol.FieldId(3), 1, protocol.FieldId(1)))
+		should.Equal(int64(4), val.Get(protocol.FieldId(4), 1, 1))
+		should.Equal("v", val.Get(protocol.FieldId(5), 0, "k"))
+	}
+}
+
+func Test_decode_array(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		input, err := c.Marshal(general.Struct{
+			protocol.FieldId(1): []byte{1, 2, 3, 4},
+			protocol.FieldId(2): general.List{Elements: []interface{}{int32(5), int32(6), int32(7)}},
+			protocol.FieldId(3): general.List{Elements: []interface{}{
+				general.Struct{protocol.FieldId(1): int32(1)},
+				general.Struct{protocol.FieldId(2): int32(4)},
+			}},
+			protocol.FieldId(4): general.List{Elements: []interface{}{
+				general.List{Elements: []interface{}{int64(1), int64(2)}},
+				general.List{Elements: []interface{}{int64(3), int64(4)}},
+			}},
+		})
+		should.NoError(err)
+		var val Fingerprint
+		should.NoError(c.Unmarshal(input, &val))
+		should.Equal(Fingerprint{
+			Hash:    [4]byte{1, 2, 3, 4},
+			Vector:  [3]int32{5, 6, 7},
+			Corners: [2]Point{{X: 1}, {Y: 4}},
+			Grid:    [2][2]int64{{1, 2}, {3, 4}},
+		}, val)
+	}
+}
+
+func Test_decode_array_length_mismatch(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		input, err := c.Marshal(general.Struct{
+			protocol.FieldId(2): general.List{Elements: []interface{}{int32(5), int32(6)}},
+		})
+		should.NoError(err)
+		var val Fingerprint
+		err = c.Unmarshal(input, &val)
+		should.Error(err)
+		should.Contains(err.Error(), "expected 3 elements but got 2")
+		input, err = c.Marshal(general.Struct{
+			protocol.FieldId(1): []byte{1, 2, 3, 4, 5},
+		})
+		should.NoError(err)
+		err = c.Unmarshal(input, &val)
+		should.Error(err)
+		should.Contains(err.Error(), "expected 4 bytes but got 5")
+	}
+}
+
+func Test_array_at_top_level(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal([1]*Point{{X: 1}})
+		should.NoError(err)
+		var val [1]*Point
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(int32(1), val[0].X)
+		output, err = c.Marshal([1]map[string]int32{{"a": 1}})
+		should.NoError(err)
+		var maps [1]map[string]int32
+		should.NoError(c.Unmarshal(output, &maps))
+		should.Equal(map[string]int32{"a": 1}, maps[0])
+	}
+}
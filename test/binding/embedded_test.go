@@ -0,0 +1,159 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type RequestHeader struct {
+	TraceId string `thrift:"traceId,1"`
+	Caller  string `thrift:"caller,2"`
+}
+
+type Pagination struct {
+	Offset int32 `thrift:"offset,10"`
+	Limit  int32 `thrift:"limit,11"`
+}
+
+type ListOrdersRequest struct {
+	RequestHeader
+	*Pagination
+	UserId int64 `thrift:"userId,3"`
+}
+
+type OverriddenRequest struct {
+	RequestHeader
+	Caller int64 `thrift:"caller,2"`
+}
+
+func Test_embedded_struct(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(ListOrdersRequest{
+			RequestHeader: RequestHeader{TraceId: "abc", Caller: "gateway"},
+			Pagination:    &Pagination{Offset: 20, Limit: 10},
+			UserId:        1024,
+		})
+		should.NoError(err)
+		var args general.Struct
+		should.NoError(c.Unmarshal(output, &args))
+		should.Equal(general.Struct{
+			protocol.FieldId(1):  "abc",
+			protocol.FieldId(2):  "gateway",
+			protocol.FieldId(3):  int64(1024),
+			protocol.FieldId(10): int32(20),
+			protocol.FieldId(11): int32(10),
+		}, args)
+		var val ListOrdersRequest
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal("abc", val.TraceId)
+		should.Equal("gateway", val.Caller)
+		should.Equal(int64(1024), val.UserId)
+		should.Equal(int32(20), val.Offset)
+		should.Equal(int32(10), val.Limit)
+	}
+}
+
+func Test_embedded_nil_pointer(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(ListOrdersRequest{UserId: 1024})
+		should.NoError(err)
+		var args general.Struct
+		should.NoError(c.Unmarshal(output, &args))
+		should.Len(args, 3)
+		var val ListOrdersRequest
+		should.NoError(c.Unmarshal(output, &val))
+		should.Nil(val.Pagination)
+	}
+}
+
+func Test_embedded_field_overridden(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(OverriddenRequest{
+			RequestHeader: RequestHeader{TraceId: "abc", Caller: "hidden"},
+			Caller:        1,
+		})
+		should.NoError(err)
+		var args general.Struct
+		should.NoError(c.Unmarshal(output, &args))
+		should.Equal(general.Struct{
+			protocol.FieldId(1): "abc",
+			protocol.FieldId(2): int64(1),
+		}, args)
+	}
+}
+
+type Envelope struct {
+	Kind    string                 `thrift:"kind,1"`
+	Payload interface{}            `thrift:"payload,2"`
+	Extras  []interface{}          `thrift:"extras,3"`
+	Labels  map[string]interface{} `thrift:"labels,4"`
+}
+
+func Test_interface_field(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(Envelope{
+			Kind:    "order",
+			Payload: general.Struct{protocol.FieldId(1): int64(100)},
+			Extras:  []interface{}{"a", "b"},
+			Labels:  map[string]interface{}{"region": int32(1)},
+		})
+		should.NoError(err)
+		var val Envelope
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal("order", val.Kind)
+		should.Equal(general.Struct{protocol.FieldId(1): int64(100)}, val.Payload)
+		should.Equal([]interface{}{"a", "b"}, val.Extras)
+		should.Equal(map[string]interface{}{"region": int32(1)}, val.Labels)
+	}
+}
+
+func Test_nil_interface_field(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(Envelope{Kind: "order"})
+		should.NoError(err)
+		var val Envelope
+		should.NoError(c.Unmarshal(output, &val))
+		should.Nil(val.Payload)
+	}
+}
+
+func Test_interface_elements_of_different_types(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		_, err := c.Marshal(Envelope{Extras: []interface{}{"a", int32(1)}})
+		should.Error(err)
+		should.Contains(err.Error(), "expected element of type String but got I32")
+		_, err = c.Marshal(Envelope{Labels: map[string]interface{}{"a": "b", "c": int32(1)}})
+		should.Error(err)
+		should.Contains(err.Error(), "expected element of type")
+		_, err = c.Marshal(Envelope{Extras: []interface{}{"a", nil}})
+		should.Error(err)
+	}
+}
+
+func Test_empty_interface_containers(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(Envelope{Extras: []interface{}{}, Labels: map[string]interface{}{}})
+		should.NoError(err)
+		var obj general.Struct
+		should.NoError(c.Unmarshal(output, &obj))
+		should.Equal(general.List{ElementType: protocol.TypeI64}, obj[protocol.FieldId(3)])
+		var val Envelope
+		should.NoError(c.Unmarshal(output, &val))
+		should.Empty(val.Extras)
+		should.Empty(val.Labels)
+	}
+	output, err := thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze().Marshal(map[string]interface{}{})
+	should.NoError(err)
+	should.Equal([]byte{byte(protocol.TypeString), byte(protocol.TypeI64), 0, 0, 0, 0}, output)
+}
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Quote struct {
+	Price int64 `thrift:"price,1"`
+}
+
+// QuoteResult is the result struct of a method, success is field 0
+type QuoteResult struct {
+	Success *Quote `thrift:"success,0"`
+}
+
+func Test_decode_field_zero_absent(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		output, err := c.Marshal(QuoteResult{})
+		should.NoError(err)
+		val := QuoteResult{Success: &Quote{Price: 1}}
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(QuoteResult{Success: &Quote{Price: 1}}, val)
+	}
+}
@@ -0,0 +1,22 @@
+package model
+
+// Node is declared out of the test package, so that static codegen can compile it into plugin
+type Node struct {
+	Name     string  `thrift:"name,1"`
+	Children []*Node `thrift:"children,2"`
+}
+
+type Reading struct {
+	Sensor int32 `thrift:"sensor,1"`
+	Value  int64 `thrift:"value,2"`
+}
+
+// Batch covers the pointer, slice and map decoders reusing values
+type Batch struct {
+	Id       int64              `thrift:"id,1"`
+	Source   *Reading           `thrift:"source,2"`
+	Samples  []int64            `thrift:"samples,3"`
+	Readings []Reading          `thrift:"readings,4"`
+	Counters map[int32]int64    `thrift:"counters,5"`
+	Sensors  map[string]Reading `thrift:"sensors,6"`
+}
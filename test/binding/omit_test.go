@@ -0,0 +1,63 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Profile struct {
+	Name     string            `thrift:"name,1"`
+	Age      int32             `thrift:"age,2,omitempty"`
+	Nickname string            `thrift:"nickname,3,omitempty"`
+	Tags     []string          `thrift:"tags,4"`
+	Attrs    map[string]string `thrift:"attrs,5"`
+	Admin    bool              `thrift:"admin,6"`
+	Manager  *Manager          `thrift:"manager,7"`
+}
+
+type Manager struct {
+	Name string `thrift:"name,1"`
+}
+
+func fieldIdsOf(should *require.Assertions, api thrifter.API, val interface{}) []protocol.FieldId {
+	output, err := api.Marshal(val)
+	should.NoError(err)
+	var args general.Struct
+	should.NoError(api.Unmarshal(output, &args))
+	var fieldIds []protocol.FieldId
+	for fieldId := protocol.FieldId(1); fieldId <= 7; fieldId++ {
+		if _, found := args[fieldId]; found {
+			fieldIds = append(fieldIds, fieldId)
+		}
+	}
+	return fieldIds
+}
+
+func Test_omit_policy(t *testing.T) {
+	should := require.New(t)
+	for _, protocolType := range []thrifter.Protocol{thrifter.ProtocolBinary, thrifter.ProtocolCompact} {
+		omitNil := thrifter.Config{Protocol: protocolType}.Froze()
+		should.Equal([]protocol.FieldId{1, 6}, fieldIdsOf(should, omitNil, Profile{}))
+		should.Equal([]protocol.FieldId{1, 2, 4, 6}, fieldIdsOf(should, omitNil, Profile{
+			Age: 1, Tags: []string{},
+		}))
+		omitZero := thrifter.Config{Protocol: protocolType, OmitPolicy: spi.OmitZero}.Froze()
+		should.Equal([]protocol.FieldId(nil), fieldIdsOf(should, omitZero, Profile{
+			Tags: []string{}, Attrs: map[string]string{},
+		}))
+		should.Equal([]protocol.FieldId{1, 5, 6, 7}, fieldIdsOf(should, omitZero, Profile{
+			Name: "a", Attrs: map[string]string{"k": "v"}, Admin: true, Manager: &Manager{},
+		}))
+		omitNever := thrifter.Config{Protocol: protocolType, OmitPolicy: spi.OmitNever}.Froze()
+		should.Equal([]protocol.FieldId{1, 4, 5, 6}, fieldIdsOf(should, omitNever, Profile{}))
+		var val Profile
+		output, err := omitNever.Marshal(Profile{})
+		should.NoError(err)
+		should.NoError(omitNever.Unmarshal(output, &val))
+		should.Equal(map[string]string{}, val.Attrs)
+	}
+}
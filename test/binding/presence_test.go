@@ -0,0 +1,88 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Account struct {
+	thrifter.Presence
+	Balance  int64    `thrift:"balance,1"`
+	Nickname string   `thrift:"nickname,2"`
+	Tags     []string `thrift:"tags,3"`
+}
+
+func Test_decode_presence(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		input, err := c.Marshal(general.Struct{
+			protocol.FieldId(1): int64(0),
+			protocol.FieldId(3): general.List{Elements: []interface{}{"vip"}},
+		})
+		should.NoError(err)
+		var account Account
+		should.NoError(c.Unmarshal(input, &account))
+		should.True(account.IsSet(1))
+		should.False(account.IsSet(2))
+		should.True(account.IsSet(3))
+		should.Equal(int64(0), account.Balance)
+		should.Equal([]string{"vip"}, account.Tags)
+	}
+}
+
+func Test_encode_presence(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		account := Account{Nickname: "bob"}
+		account.Set(1)
+		output, err := c.Marshal(account)
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(general.Struct{
+			protocol.FieldId(1): int64(0),
+		}, val)
+		account.Unset(1)
+		output, err = c.Marshal(account)
+		should.NoError(err)
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(general.Struct{}, val)
+	}
+}
+
+func Test_encode_unset_non_zero_field(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		account := Account{Balance: 5, Nickname: "bob"}
+		account.Set(1)
+		account.Set(2)
+		account.Unset(2)
+		output, err := c.Marshal(account)
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(general.Struct{
+			protocol.FieldId(1): int64(5),
+		}, val)
+	}
+}
+
+func Test_presence_negative_field_id(t *testing.T) {
+	should := require.New(t)
+	var presence protocol.Presence
+	presence.Set(-1)
+	presence.Set(-65)
+	should.True(presence.IsSet(-1))
+	should.True(presence.IsSet(-65))
+	should.False(presence.IsSet(0))
+	should.False(presence.IsSet(63))
+	should.False(presence.IsSet(-2))
+	presence.Unset(-1)
+	should.False(presence.IsSet(-1))
+	presence.Reset()
+	should.False(presence.IsSet(-65))
+}
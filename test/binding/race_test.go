@@ -0,0 +1,7 @@
+//go:build race
+// +build race
+
+package test
+
+// raceEnabled skips the allocation tests, the race detector allocates by itself
+const raceEnabled = true
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/batchcorp/thrift-iterator/test/binding/model"
+	"github.com/stretchr/testify/require"
+	"github.com/v2pro/wombat/generic"
+	"testing"
+)
+
+func init() {
+	generic.DynamicCompilationEnabled = true
+}
+
+type Employee struct {
+	Name    string               `thrift:"name,1"`
+	Reports []*Employee          `thrift:"reports,2"`
+	Manager *Employee            `thrift:"manager,3"`
+	Mentors map[string]*Employee `thrift:"mentors,4"`
+}
+
+type Expr struct {
+	Op   string `thrift:"op,1"`
+	Args []Expr `thrift:"args,2"`
+}
+
+type Chain struct {
+	Next *Chain `thrift:"next,1"`
+}
+
+func chainOf(depth int) *Chain {
+	var chain *Chain
+	for i := 0; i < depth; i++ {
+		chain = &Chain{Next: chain}
+	}
+	return chain
+}
+
+func Test_recursive_struct(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		ceo := Employee{
+			Name: "ceo",
+			Reports: []*Employee{
+				{Name: "cto", Reports: []*Employee{{Name: "dev"}}},
+				{Name: "cfo", Mentors: map[string]*Employee{"board": {Name: "chair"}}},
+			},
+			Manager: &Employee{Name: "board"},
+		}
+		output, err := c.Marshal(ceo)
+		should.NoError(err)
+		var val Employee
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(ceo, val)
+		var obj general.Struct
+		should.NoError(c.Unmarshal(output, &obj))
+		should.Equal("dev", obj.Get(protocol.FieldId(2), 0, protocol.FieldId(2), 0, protocol.FieldId(1)))
+	}
+}
+
+func Test_recursive_slice_of_struct(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		expr := Expr{Op: "+", Args: []Expr{{Op: "1"}, {Op: "*", Args: []Expr{{Op: "2"}, {Op: "3"}}}}}
+		output, err := c.Marshal(expr)
+		should.NoError(err)
+		var val Expr
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(expr, val)
+	}
+}
+
+func Test_recursive_struct_by_codegen(t *testing.T) {
+	should := require.New(t)
+	node := model.Node{Name: "root", Children: []*model.Node{
+		{Name: "a", Children: []*model.Node{{Name: "b"}}},
+		{Name: "c"},
+	}}
+	for _, proto := range []thrifter.Protocol{thrifter.ProtocolBinary, thrifter.ProtocolCompact} {
+		api := thrifter.Config{Protocol: proto, StaticCodegen: true}.Froze()
+		output, err := api.Marshal(node)
+		should.NoError(err)
+		var val model.Node
+		should.NoError(api.Unmarshal(output, &val))
+		should.Equal(node, val)
+		var obj general.Struct
+		should.NoError(api.Unmarshal(output, &obj))
+		should.Equal("b", obj.Get(protocol.FieldId(2), 0, protocol.FieldId(2), 0, protocol.FieldId(1)))
+	}
+}
+
+func Test_max_depth(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range []thrifter.Protocol{thrifter.ProtocolBinary, thrifter.ProtocolCompact} {
+		unlimited := thrifter.Config{Protocol: proto, MaxDepth: -1}.Froze()
+		output, err := unlimited.Marshal(chainOf(65))
+		should.NoError(err)
+		var val Chain
+		should.NoError(unlimited.Unmarshal(output, &val))
+		err = thrifter.Config{Protocol: proto}.Froze().Unmarshal(output, &val)
+		should.Error(err)
+		should.Contains(err.Error(), "nested deeper than 64")
+		limited := thrifter.Config{Protocol: proto, MaxDepth: 3}.Froze()
+		_, err = limited.Marshal(chainOf(4))
+		should.Error(err)
+		output, err = limited.Marshal(chainOf(3))
+		should.NoError(err)
+		should.NoError(limited.Unmarshal(output, &val))
+	}
+}
+
+type Shallow struct {
+	Name string `thrift:"name,1"`
+}
+
+func Test_max_depth_of_containers(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range []thrifter.Protocol{thrifter.ProtocolBinary, thrifter.ProtocolCompact} {
+		nested := general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(1)}}
+		for i := 0; i < 64; i++ {
+			nested = general.List{ElementType: protocol.TypeList, Elements: []interface{}{nested}}
+		}
+		unlimited := thrifter.Config{Protocol: proto, MaxDepth: -1}.Froze()
+		output, err := unlimited.Marshal(general.Struct{protocol.FieldId(2): nested})
+		should.NoError(err)
+		var obj general.Struct
+		should.NoError(unlimited.Unmarshal(output, &obj))
+		var shallow Shallow
+		should.NoError(unlimited.Unmarshal(output, &shallow))
+		api := thrifter.Config{Protocol: proto}.Froze()
+		err = api.Unmarshal(output, &obj)
+		should.Error(err)
+		should.Contains(err.Error(), "nested deeper than 64")
+		err = api.Unmarshal(output, &shallow)
+		should.Error(err)
+		should.Contains(err.Error(), "nested deeper than 64")
+		err = thrifter.TranscodeValue(api.NewIterator(nil, output), api.NewStream(nil, nil), protocol.TypeStruct)
+		should.Error(err)
+		should.Contains(err.Error(), "nested deeper than 64")
+		// the iterator not counting containers still works, only the structs are limited
+		err = thrifter.TranscodeValue(uncountedIterator{api.NewIterator(nil, output)}, api.NewStream(nil, nil), protocol.TypeStruct)
+		should.NoError(err)
+	}
+}
+
+// uncountedIterator hides spi.ContainerCounter, like Iterator implemented outside
+type uncountedIterator struct {
+	spi.Iterator
+}
+
+func Test_encode_cyclic_value(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		cyclic := &Chain{}
+		cyclic.Next = cyclic
+		_, err := c.Marshal(cyclic)
+		should.Error(err)
+	}
+}
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/batchcorp/thrift-iterator/test/binding/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Reading struct {
+	Sensor int32 `thrift:"sensor,1"`
+	Value  int64 `thrift:"value,2"`
+}
+
+type Batch struct {
+	Id       int64           `thrift:"id,1"`
+	Source   *Reading        `thrift:"source,2"`
+	Samples  []int64         `thrift:"samples,3"`
+	Readings []Reading       `thrift:"readings,4"`
+	Latest   []*Reading      `thrift:"latest,5"`
+	Counters map[int32]int64 `thrift:"counters,6"`
+}
+
+var reuseCfg = thrifter.Config{Protocol: thrifter.ProtocolBinary, ReuseValues: true}
+
+func sampleBatch(id int64) Batch {
+	return Batch{
+		Id:       id,
+		Source:   &Reading{Sensor: 1, Value: id},
+		Samples:  []int64{id, id + 1, id + 2},
+		Readings: []Reading{{2, id}, {3, id}},
+		Latest:   []*Reading{{4, id}},
+		Counters: map[int32]int64{1: id},
+	}
+}
+
+func Test_decode_reusing_values(t *testing.T) {
+	should := require.New(t)
+	api := reuseCfg.Froze()
+	first, err := api.Marshal(sampleBatch(1))
+	should.NoError(err)
+	second, err := api.Marshal(Batch{Id: 2, Source: &Reading{Value: 2}, Samples: []int64{2}, Counters: map[int32]int64{2: 2}})
+	should.NoError(err)
+	var val Batch
+	should.NoError(api.Unmarshal(first, &val))
+	should.Equal(sampleBatch(1), val)
+	source, samples, latest := val.Source, val.Samples, val.Latest[0]
+	should.NoError(api.Unmarshal(first, &val))
+	should.True(source == val.Source)
+	should.True(&samples[0] == &val.Samples[0])
+	should.True(latest == val.Latest[0])
+	should.NoError(api.Unmarshal(second, &val))
+	should.True(source == val.Source)
+	should.Equal(Reading{Value: 2}, *val.Source)
+	should.Equal([]int64{2}, val.Samples)
+	should.Equal(map[int32]int64{2: 2}, val.Counters)
+	// missing from the message, kept as it is
+	should.Equal([]Reading{{2, 1}, {3, 1}}, val.Readings)
+}
+
+func Test_decode_without_reusing_values(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		input, err := c.Marshal(sampleBatch(1))
+		should.NoError(err)
+		var val Batch
+		should.NoError(c.Unmarshal(input, &val))
+		source := val.Source
+		val.Counters = map[int32]int64{2: 2}
+		should.NoError(c.Unmarshal(input, &val))
+		should.False(source == val.Source)
+		should.Equal(map[int32]int64{1: 1, 2: 2}, val.Counters)
+	}
+}
+
+func Test_decode_reusing_values_without_allocation(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are counted wrong with race detector")
+	}
+	should := require.New(t)
+	for _, protocol := range []thrifter.Protocol{thrifter.ProtocolBinary, thrifter.ProtocolCompact} {
+		api := thrifter.Config{Protocol: protocol, ReuseValues: true}.Froze()
+		input, err := api.Marshal(sampleBatch(1))
+		should.NoError(err)
+		var val Batch
+		should.NoError(api.Unmarshal(input, &val))
+		should.Equal(float64(0), testing.AllocsPerRun(100, func() {
+			api.Unmarshal(input, &val)
+		}))
+	}
+}
+
+func Test_decode_reusing_values_by_codegen(t *testing.T) {
+	should := require.New(t)
+	api := thrifter.Config{Protocol: thrifter.ProtocolBinary, StaticCodegen: true, ReuseValues: true}.Froze()
+	first, err := reuseCfg.Froze().Marshal(model.Batch{
+		Id:       1,
+		Source:   &model.Reading{Sensor: 1, Value: 1},
+		Samples:  []int64{1, 2, 3},
+		Readings: []model.Reading{{2, 1}},
+		Counters: map[int32]int64{1: 1},
+		Sensors:  map[string]model.Reading{"a": {1, 1}, "b": {Value: 2}},
+	})
+	should.NoError(err)
+	second, err := reuseCfg.Froze().Marshal(model.Batch{
+		Id:       2,
+		Source:   &model.Reading{Value: 2},
+		Samples:  []int64{2},
+		Counters: map[int32]int64{2: 2},
+		Sensors:  map[string]model.Reading{"b": {Value: 3}},
+	})
+	should.NoError(err)
+	var val model.Batch
+	should.NoError(api.Unmarshal(first, &val))
+	should.Equal(map[string]model.Reading{"a": {1, 1}, "b": {Value: 2}}, val.Sensors)
+	source, samples, counters := val.Source, val.Samples, val.Counters
+	should.NoError(api.Unmarshal(second, &val))
+	should.True(source == val.Source)
+	should.Equal(model.Reading{Value: 2}, *val.Source)
+	should.True(&samples[0] == &val.Samples[0])
+	should.Equal([]int64{2}, val.Samples)
+	should.Equal(map[int32]int64{2: 2}, counters)
+	should.Equal(map[string]model.Reading{"b": {Value: 3}}, val.Sensors)
+	// missing from the message, kept as it is
+	should.Equal([]model.Reading{{2, 1}}, val.Readings)
+	if raceEnabled {
+		return
+	}
+	should.Equal(float64(0), testing.AllocsPerRun(100, func() {
+		api.Unmarshal(second, &val)
+	}))
+}
+
+func Benchmark_decode_reusing_values(b *testing.B) {
+	api := reuseCfg.Froze()
+	input, _ := api.Marshal(sampleBatch(1))
+	var val Batch
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := api.Unmarshal(input, &val); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_decode_without_reusing_values(b *testing.B) {
+	api := thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze()
+	input, _ := api.Marshal(sampleBatch(1))
+	var val Batch
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := api.Unmarshal(input, &val); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
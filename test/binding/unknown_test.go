@@ -0,0 +1,98 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type OldOrder struct {
+	OrderId int64      `thrift:"orderId,1"`
+	Unknown raw.Struct `thrift:",unknown"`
+}
+
+func Test_unknown_fields_round_trip(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		newOrder := general.Struct{
+			protocol.FieldId(1): int64(1024),
+			protocol.FieldId(2): "added by newer version",
+			protocol.FieldId(3): true,
+			protocol.FieldId(4): general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(1), int32(2)}},
+			protocol.FieldId(5): general.Struct{protocol.FieldId(1): false},
+		}
+		input, err := c.Marshal(newOrder)
+		should.NoError(err)
+		var oldOrder OldOrder
+		should.NoError(c.Unmarshal(input, &oldOrder))
+		should.Equal(int64(1024), oldOrder.OrderId)
+		should.Len(oldOrder.Unknown, 4)
+		should.Equal(protocol.TypeString, oldOrder.Unknown[protocol.FieldId(2)].Type)
+		oldOrder.OrderId = 2048
+		output, err := c.Marshal(oldOrder)
+		should.NoError(err)
+		var roundTripped general.Struct
+		should.NoError(c.Unmarshal(output, &roundTripped))
+		newOrder[protocol.FieldId(1)] = int64(2048)
+		should.Equal(newOrder, roundTripped)
+	}
+}
+
+func Test_unknown_field_not_overriding_known(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(OldOrder{
+			OrderId: 1,
+			Unknown: raw.Struct{protocol.FieldId(1): raw.StructField{
+				Type: protocol.TypeString, Buffer: []byte{0, 0, 0, 0}}},
+		})
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(general.Struct{protocol.FieldId(1): int64(1)}, val)
+	}
+}
+
+type NewOrder struct {
+	OrderId  int64  `thrift:"orderId,1"`
+	Paid     bool   `thrift:"paid,2"`
+	Comment  string `thrift:"comment,3"`
+	Shipped  bool   `thrift:"shipped,4"`
+	Quantity int32  `thrift:"quantity,20"`
+}
+
+func Test_unknown_fields_written_in_order(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		input, err := c.Marshal(NewOrder{
+			OrderId: 1024, Paid: true, Comment: "gift", Shipped: false, Quantity: 3})
+		should.NoError(err)
+		for i := 0; i < 10; i++ {
+			var oldOrder OldOrder
+			should.NoError(c.Unmarshal(input, &oldOrder))
+			should.Len(oldOrder.Unknown, 4)
+			output, err := c.Marshal(oldOrder)
+			should.NoError(err)
+			should.Equal(input, output)
+		}
+	}
+}
+
+func Test_unknown_fields_reset_by_decode(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		withUnknown, err := c.Marshal(NewOrder{OrderId: 1, Comment: "gift"})
+		should.NoError(err)
+		withoutUnknown, err := c.Marshal(OldOrder{OrderId: 2})
+		should.NoError(err)
+		var oldOrder OldOrder
+		should.NoError(c.Unmarshal(withUnknown, &oldOrder))
+		should.Len(oldOrder.Unknown, 4)
+		should.NoError(c.Unmarshal(withoutUnknown, &oldOrder))
+		should.Equal(int64(2), oldOrder.OrderId)
+		should.Len(oldOrder.Unknown, 0)
+	}
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/client"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/server"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type EchoArgs struct {
+	Message string        `thrift:"message,1"`
+	Delay   time.Duration `thrift:"delay,2"`
+}
+
+type EchoResult struct {
+	Success *string `thrift:"success,0"`
+}
+
+var apis = []thrifter.API{
+	thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze(),
+	thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze(),
+}
+
+func newServer(api thrifter.API, framed bool) *server.Server {
+	srv := server.New(server.Config{API: api, Framed: framed})
+	srv.Handle("echo", EchoArgs{}, func(ctx context.Context, args interface{}) (interface{}, error) {
+		echoArgs := args.(*EchoArgs)
+		if echoArgs.Message == "" {
+			return nil, errors.New("empty message")
+		}
+		time.Sleep(echoArgs.Delay)
+		return EchoResult{Success: &echoArgs.Message}, nil
+	})
+	return srv
+}
+
+func pipeDialer(srv *server.Server) func(ctx context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		clientSide, serverSide := net.Pipe()
+		go srv.ServeConn(serverSide)
+		return clientSide, nil
+	}
+}
+
+func Test_call(t *testing.T) {
+	should := require.New(t)
+	for _, api := range apis {
+		for _, framed := range []bool{false, true} {
+			for _, pipelined := range []bool{false, true} {
+				srv := newServer(api, framed)
+				c := client.New(client.Config{API: api, Framed: framed, Pipelined: pipelined, Dial: pipeDialer(srv)})
+				var result EchoResult
+				should.NoError(c.Call(context.Background(), "echo", EchoArgs{Message: "hello"}, &result))
+				should.Equal("hello", *result.Success)
+				err := c.Call(context.Background(), "echo", EchoArgs{}, &result)
+				should.Error(err)
+				should.Contains(err.Error(), "empty message")
+				err = c.Call(context.Background(), "unknown", EchoArgs{}, nil)
+				should.Error(err)
+				should.Contains(err.Error(), "unknown method")
+				var exception *protocol.ApplicationException
+				should.True(errors.As(err, &exception))
+				should.Equal(protocol.ExceptionUnknownMethod, exception.Type)
+				result = EchoResult{}
+				should.NoError(c.Call(context.Background(), "echo", EchoArgs{Message: "again"}, &result))
+				should.Equal("again", *result.Success)
+				should.NoError(c.Close())
+				should.NoError(srv.Close())
+			}
+		}
+	}
+}
+
+func Test_call_deadline(t *testing.T) {
+	should := require.New(t)
+	for _, pipelined := range []bool{false, true} {
+		srv := newServer(apis[0], false)
+		c := client.New(client.Config{Pipelined: pipelined, Dial: pipeDialer(srv)})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		var result EchoResult
+		err := c.Call(ctx, "echo", EchoArgs{Message: "slow", Delay: time.Second}, &result)
+		cancel()
+		should.Equal(context.DeadlineExceeded, err)
+		should.NoError(c.Close())
+		should.NoError(srv.Close())
+	}
+}
+
+func Test_concurrent_calls(t *testing.T) {
+	should := require.New(t)
+	for _, pipelined := range []bool{false, true} {
+		srv := newServer(apis[1], true)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		should.NoError(err)
+		go srv.Serve(listener)
+		c := client.New(client.Config{API: apis[1], Framed: true, Pipelined: pipelined,
+			Addr: listener.Addr().String(), MaxConns: 4})
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(message string) {
+				defer wg.Done()
+				var result EchoResult
+				if err := c.Call(context.Background(), "echo", EchoArgs{Message: message}, &result); err != nil {
+					errs <- err
+					return
+				}
+				if *result.Success != message {
+					errs <- errors.New("reply mismatch: " + message + " vs " + *result.Success)
+				}
+			}(string(rune('a' + i)))
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			should.NoError(err)
+		}
+		should.NoError(c.Close())
+		should.NoError(srv.Close())
+	}
+}
+
+func Test_pipelined_write_deadline(t *testing.T) {
+	should := require.New(t)
+	// the server never reads, the call holding the connection blocks in write, the other one waits to write
+	stalled := func(ctx context.Context) (net.Conn, error) {
+		clientSide, _ := net.Pipe()
+		return clientSide, nil
+	}
+	c := client.New(client.Config{Pipelined: true, Dial: stalled})
+	errs := make(chan error, 2)
+	for _, timeout := range []time.Duration{100 * time.Millisecond, 50 * time.Millisecond} {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		go func() {
+			errs <- c.Call(ctx, "echo", EchoArgs{Message: "hello"}, &EchoResult{})
+		}()
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			should.Equal(context.DeadlineExceeded, err)
+		case <-time.After(time.Second):
+			should.Fail("pipelined call not bounded by ctx")
+		}
+	}
+	should.NoError(c.Close())
+}
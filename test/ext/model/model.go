@@ -0,0 +1,16 @@
+package model
+
+import (
+	"math/big"
+	"net"
+	"time"
+)
+
+// PointerEvent is declared out of the test package, so that static codegen can compile it into plugin
+type PointerEvent struct {
+	ExpiresAt *time.Time     `thrift:"expiresAt,1,rfc3339"`
+	Interval  *time.Duration `thrift:"interval,2,millis"`
+	Amount    *big.Int       `thrift:"amount,3"`
+	Target    *net.IP        `thrift:"target,4,string"`
+	Missing   *time.Time     `thrift:"missing,5,nanos"`
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/ext/stdtypes"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/test/ext/model"
+	"github.com/stretchr/testify/require"
+	"github.com/v2pro/wombat/generic"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func init() {
+	generic.DynamicCompilationEnabled = true
+}
+
+func Test_stdtypes_pointer_fields(t *testing.T) {
+	should := require.New(t)
+	expiresAt := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := 1500 * time.Millisecond
+	amount := big.NewInt(-42)
+	target := net.ParseIP("::1")
+	// each static codegen config compiles its own plugins, so they are kept few
+	for _, cfg := range []thrifter.Config{
+		{Protocol: thrifter.ProtocolBinary},
+		{Protocol: thrifter.ProtocolCompact},
+		{Protocol: thrifter.ProtocolBinary, StaticCodegen: true},
+		{Protocol: thrifter.ProtocolCompact, StaticCodegen: true, ReuseValues: true},
+	} {
+		api := cfg.AddExtension(&stdtypes.Extension{}).Froze()
+		output, err := api.Marshal(model.PointerEvent{
+			ExpiresAt: &expiresAt,
+			Interval:  &interval,
+			Amount:    amount,
+			Target:    &target,
+		})
+		should.NoError(err)
+		var args general.Struct
+		should.NoError(api.Unmarshal(output, &args))
+		should.Equal("2018-01-01T00:00:00Z", args[protocol.FieldId(1)])
+		should.Equal(int64(1500), args[protocol.FieldId(2)])
+		should.Equal("-42", args[protocol.FieldId(3)])
+		should.Equal("::1", args[protocol.FieldId(4)])
+		should.NotContains(args, protocol.FieldId(5))
+		var val model.PointerEvent
+		should.NoError(api.Unmarshal(output, &val))
+		should.True(expiresAt.Equal(*val.ExpiresAt))
+		should.Equal(interval, *val.Interval)
+		should.Equal(0, amount.Cmp(val.Amount))
+		should.Equal("::1", val.Target.String())
+		should.Nil(val.Missing)
+	}
+}
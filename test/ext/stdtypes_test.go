@@ -0,0 +1,97 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/ext/stdtypes"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var stdtypesApis = []thrifter.API{
+	thrifter.Config{Protocol: thrifter.ProtocolBinary}.AddExtension(&stdtypes.Extension{}).Froze(),
+	thrifter.Config{Protocol: thrifter.ProtocolCompact}.AddExtension(&stdtypes.Extension{}).Froze(),
+}
+
+type Event struct {
+	CreatedAt time.Time     `thrift:"createdAt,1"`
+	UpdatedAt time.Time     `thrift:"updatedAt,2,nanos"`
+	ExpiresAt *time.Time    `thrift:"expiresAt,3,rfc3339"`
+	Timeout   time.Duration `thrift:"timeout,4"`
+	Interval  time.Duration `thrift:"interval,5,millis"`
+	Amount    *big.Int      `thrift:"amount,6"`
+	Source    net.IP        `thrift:"source,7"`
+	Target    net.IP        `thrift:"target,8,string"`
+	Callback  url.URL       `thrift:"callback,9"`
+}
+
+func Test_stdtypes(t *testing.T) {
+	should := require.New(t)
+	createdAt := time.Date(2017, 12, 1, 8, 30, 0, 123000000, time.UTC)
+	updatedAt := time.Date(2017, 12, 1, 8, 30, 0, 123456789, time.UTC)
+	expiresAt := time.Date(2018, 1, 1, 0, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	callback, _ := url.Parse("http://example.com/callback?id=1")
+	amount, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	for _, api := range stdtypesApis {
+		output, err := api.Marshal(Event{
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+			ExpiresAt: &expiresAt,
+			Timeout:   3 * time.Second,
+			Interval:  1500 * time.Millisecond,
+			Amount:    amount,
+			Source:    net.ParseIP("10.0.0.1").To4(),
+			Target:    net.ParseIP("::1"),
+			Callback:  *callback,
+		})
+		should.NoError(err)
+		var args general.Struct
+		should.NoError(api.Unmarshal(output, &args))
+		should.Equal(int64(1512117000123), args[protocol.FieldId(1)])
+		should.Equal(updatedAt.UnixNano(), args[protocol.FieldId(2)])
+		should.Equal("2018-01-01T00:00:00+08:00", args[protocol.FieldId(3)])
+		should.Equal(int64(3*time.Second), args[protocol.FieldId(4)])
+		should.Equal(int64(1500), args[protocol.FieldId(5)])
+		should.Equal("-123456789012345678901234567890", args[protocol.FieldId(6)])
+		should.Equal("::1", args[protocol.FieldId(8)])
+		should.Equal("http://example.com/callback?id=1", args[protocol.FieldId(9)])
+		var val Event
+		should.NoError(api.Unmarshal(output, &val))
+		should.True(createdAt.Equal(val.CreatedAt))
+		should.True(updatedAt.Equal(val.UpdatedAt))
+		should.True(expiresAt.Equal(*val.ExpiresAt))
+		should.Equal(3*time.Second, val.Timeout)
+		should.Equal(1500*time.Millisecond, val.Interval)
+		should.Equal(0, amount.Cmp(val.Amount))
+		should.Equal("10.0.0.1", val.Source.String())
+		should.Equal("::1", val.Target.String())
+		should.Equal(*callback, val.Callback)
+	}
+}
+
+func Test_stdtypes_top_level(t *testing.T) {
+	should := require.New(t)
+	now := time.Unix(1512117000, 0)
+	for _, api := range stdtypesApis {
+		output, err := api.Marshal(now)
+		should.NoError(err)
+		var val time.Time
+		should.NoError(api.Unmarshal(output, &val))
+		should.True(now.Equal(val))
+	}
+}
+
+func Test_stdtypes_invalid(t *testing.T) {
+	should := require.New(t)
+	for _, api := range stdtypesApis {
+		output, err := api.Marshal(general.Struct{protocol.FieldId(3): "not a time"})
+		should.NoError(err)
+		var val Event
+		should.Error(api.Unmarshal(output, &val))
+	}
+}
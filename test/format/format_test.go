@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/transport"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"testing"
+)
+
+type PingArgs struct {
+	Message string  `thrift:"message,1"`
+	Numbers []int32 `thrift:"numbers,2"`
+	Flag    bool    `thrift:"flag,3"`
+}
+
+func pingMessage(name string) general.Message {
+	return general.Message{
+		MessageHeader: protocol.MessageHeader{MessageName: name, MessageType: protocol.MessageTypeCall, SeqId: 7},
+		Arguments:     general.Struct{protocol.FieldId(1): "hello"},
+	}
+}
+
+var formats = []thrifter.Format{
+	{Protocol: thrifter.ProtocolBinary},
+	{Protocol: thrifter.ProtocolCompact},
+	{Protocol: thrifter.ProtocolBinary, Framed: true},
+	{Protocol: thrifter.ProtocolCompact, Framed: true},
+	{Protocol: thrifter.ProtocolBinary, Framed: true, Header: true},
+	{Protocol: thrifter.ProtocolCompact, Framed: true, Header: true},
+}
+
+func Test_auto_decoder(t *testing.T) {
+	should := require.New(t)
+	for _, format := range formats {
+		var buf bytes.Buffer
+		encoder := thrifter.NewFormatEncoder(&buf, format)
+		for i := 1; i <= 2; i++ {
+			should.NoError(encoder.EncodeMessageHeader(protocol.MessageHeader{
+				MessageName: "ping", MessageType: protocol.MessageTypeCall, SeqId: protocol.SeqId(i)}))
+			should.NoError(encoder.Encode(PingArgs{Message: "hello", Numbers: []int32{int32(i)}, Flag: true}))
+		}
+		decoder := thrifter.NewAutoDecoder(&buf)
+		for i := 1; i <= 2; i++ {
+			header, err := decoder.DecodeMessageHeader()
+			should.NoError(err)
+			should.Equal(protocol.SeqId(i), header.SeqId)
+			should.Equal(format, decoder.Format())
+			var args PingArgs
+			should.NoError(decoder.Decode(&args))
+			should.Equal(PingArgs{Message: "hello", Numbers: []int32{int32(i)}, Flag: true}, args)
+		}
+		_, err := decoder.DecodeMessageHeader()
+		should.Error(err)
+	}
+}
+
+func Test_reply_in_kind(t *testing.T) {
+	should := require.New(t)
+	for _, format := range formats {
+		var request, reply bytes.Buffer
+		should.NoError(thrifter.NewFormatEncoder(&request, format).EncodeMessage(pingMessage("ping")))
+		decoder := thrifter.NewAutoDecoder(&request)
+		msg, err := decoder.DecodeMessage()
+		should.NoError(err)
+		msg.MessageType = protocol.MessageTypeReply
+		should.NoError(thrifter.NewFormatEncoder(&reply, decoder.Format()).EncodeMessage(msg))
+		replyDecoder := thrifter.NewAutoDecoder(&reply)
+		replied, err := replyDecoder.DecodeMessage()
+		should.NoError(err)
+		should.Equal(msg, replied)
+		should.Equal(format, replyDecoder.Format())
+	}
+}
+
+func Test_header_frame_layout(t *testing.T) {
+	should := require.New(t)
+	var buf bytes.Buffer
+	encoder := thrifter.NewFormatEncoder(&buf, thrifter.Format{Protocol: thrifter.ProtocolCompact, Header: true})
+	should.NoError(encoder.EncodeMessage(pingMessage("p")))
+	should.Equal([]byte{
+		0, 0, 0, byte(buf.Len() - 4), 0x0f, 0xff, 0, 0, 0, 0, 0, 7, 0, 1, 2, 0, 0, 0,
+		0x82}, buf.Bytes()[:19])
+}
+
+func Test_framed_compatible_with_transport(t *testing.T) {
+	should := require.New(t)
+	clientSide, serverSide := net.Pipe()
+	api := thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze()
+	conn := transport.NewConn(api, serverSide, true)
+	go func() {
+		encoder := api.NewFormatEncoder(clientSide, thrifter.Format{Framed: true})
+		encoder.EncodeMessage(pingMessage("ping"))
+	}()
+	header, err := conn.ReadMessageHeader()
+	should.NoError(err)
+	should.Equal("ping", header.MessageName)
+	var args PingArgs
+	should.NoError(conn.ReadMessageBody(&args))
+	should.Equal("hello", args.Message)
+	clientSide.Close()
+	conn.Close()
+}
+
+func Test_unknown_format(t *testing.T) {
+	should := require.New(t)
+	decoder := thrifter.NewAutoDecoder(bytes.NewReader([]byte{0, 0, 0, 4, 'p', 'i', 'n', 'g'}))
+	_, err := decoder.DecodeMessageHeader()
+	should.True(errors.Is(err, thrifter.ErrUnknownFormat))
+	decoder.Reset(nil, []byte{})
+	_, err = decoder.DecodeMessageHeader()
+	should.Equal(io.EOF, err)
+}
+
+func Test_header_protocol_must_not_change(t *testing.T) {
+	should := require.New(t)
+	var buf bytes.Buffer
+	should.NoError(thrifter.NewFormatEncoder(&buf, thrifter.Format{
+		Protocol: thrifter.ProtocolBinary, Header: true}).EncodeMessage(pingMessage("first")))
+	should.NoError(thrifter.NewFormatEncoder(&buf, thrifter.Format{
+		Protocol: thrifter.ProtocolCompact, Header: true}).EncodeMessage(pingMessage("second")))
+	decoder := thrifter.NewAutoDecoder(&buf)
+	msg, err := decoder.DecodeMessage()
+	should.NoError(err)
+	should.Equal("first", msg.MessageName)
+	_, err = decoder.DecodeMessage()
+	should.Error(err)
+	should.Contains(err.Error(), "THeader protocol id changed")
+}
+
+func Test_frame_too_large(t *testing.T) {
+	should := require.New(t)
+	tooLarge := []byte{0x7f, 0, 0, 0, 0x80, 0x01, 0, 1}
+	_, err := thrifter.NewAutoDecoder(bytes.NewReader(tooLarge)).DecodeMessageHeader()
+	should.Error(err)
+	should.Contains(err.Error(), thrifter.ErrFrameTooLarge.Error())
+	clientSide, serverSide := net.Pipe()
+	conn := transport.NewConn(nil, serverSide, true)
+	go clientSide.Write(tooLarge)
+	_, err = conn.ReadMessageHeader()
+	should.True(errors.Is(err, thrifter.ErrFrameTooLarge))
+	clientSide.Close()
+	conn.Close()
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Coordinate struct {
+	X int32 `thrift:"x,1"`
+	Y int32 `thrift:"y,2"`
+}
+
+type Tagged struct {
+	Name *string `thrift:"name,1"`
+}
+
+func Test_struct_key(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		cells := map[Coordinate]string{{1, 2}: "a", {3, 4}: "b"}
+		output, err := c.Marshal(cells)
+		should.NoError(err)
+		var val map[Coordinate]string
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(cells, val)
+		var obj general.OrderedMap
+		should.NoError(c.Unmarshal(output, &obj))
+		should.Len(obj.Entries, 2)
+		should.Equal("b", obj.Get(general.Struct{protocol.FieldId(1): int32(3), protocol.FieldId(2): int32(4)}))
+		var generalMap general.Map
+		err = c.Unmarshal(output, &generalMap)
+		should.Error(err)
+		should.Contains(err.Error(), "general.OrderedMap")
+	}
+}
+
+func Test_list_key(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		three := general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(3)}}
+		obj := general.OrderedMap{KeyType: protocol.TypeList, ElementType: protocol.TypeString, Entries: []general.MapEntry{
+			{Key: general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(1), int32(2)}}, Element: "a"},
+			{Key: three, Element: "b"},
+		}}
+		output, err := c.Marshal(general.Struct{protocol.FieldId(1): obj})
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(obj, val[protocol.FieldId(1)])
+		reencoded, err := c.Marshal(val)
+		should.NoError(err)
+		should.Equal(output, reencoded)
+		should.Equal("b", val.Get(protocol.FieldId(1), three))
+	}
+}
+
+func Test_array_and_pointer_shaped_keys(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		vectors := map[[2]int32]bool{{1, 2}: true}
+		output, err := c.Marshal(vectors)
+		should.NoError(err)
+		var val map[[2]int32]bool
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(vectors, val)
+		name := "x"
+		tagged := map[Tagged]Tagged{{Name: &name}: {Name: &name}}
+		output, err = c.Marshal(tagged)
+		should.NoError(err)
+		var obj general.OrderedMap
+		should.NoError(c.Unmarshal(output, &obj))
+		should.Equal(general.OrderedMap{KeyType: protocol.TypeStruct, ElementType: protocol.TypeStruct, Entries: []general.MapEntry{{
+			Key:     general.Struct{protocol.FieldId(1): "x"},
+			Element: general.Struct{protocol.FieldId(1): "x"},
+		}}}, obj)
+	}
+}
+
+func Test_raw_complex_key(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		output, err := c.Marshal(map[Coordinate]string{{1, 2}: "a"})
+		should.NoError(err)
+		var val raw.Map
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(protocol.TypeStruct, val.KeyType)
+		should.Len(val.Entries, 1)
+		for key, entry := range val.Entries {
+			should.Equal(string(entry.Key), key)
+		}
+		reencoded, err := c.Marshal(val)
+		should.NoError(err)
+		should.Equal(output, reencoded)
+	}
+}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_empty_container_keeps_type(t *testing.T) {
+	should := require.New(t)
+	containers := []interface{}{
+		general.List{ElementType: protocol.TypeString},
+		general.Map{KeyType: protocol.TypeString, ElementType: protocol.TypeI32},
+	}
+	for _, c := range test.Combinations {
+		for _, container := range containers {
+			// one field per struct, so that the bytes do not depend on the order of fields
+			output, err := c.Marshal(general.Struct{protocol.FieldId(1): container})
+			should.NoError(err)
+			var val general.Struct
+			should.NoError(c.Unmarshal(output, &val))
+			reencoded, err := c.Marshal(val)
+			should.NoError(err)
+			should.Equal(output, reencoded)
+		}
+		output, err := c.Marshal(general.Struct{protocol.FieldId(1): containers[0]})
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(protocol.TypeString, val[protocol.FieldId(1)].(general.List).ElementType)
+	}
+}
+
+func Test_empty_list_round_trip(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		output, err := c.Marshal(general.List{ElementType: protocol.TypeString})
+		should.NoError(err)
+		var val general.List
+		should.NoError(c.Unmarshal(output, &val))
+		reencoded, err := c.Marshal(val)
+		should.NoError(err)
+		should.Equal(output, reencoded)
+	}
+}
+
+func Test_list_of_set_round_trip(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		stream := c.CreateStream()
+		stream.WriteListHeader(protocol.TypeSet, 1)
+		stream.WriteListHeader(protocol.TypeI32, 1)
+		stream.WriteInt32(1)
+		var val general.List
+		should.NoError(c.Unmarshal(stream.Buffer(), &val))
+		should.Equal(protocol.TypeSet, val.ElementType)
+		reencoded, err := c.Marshal(val)
+		should.NoError(err)
+		should.Equal(stream.Buffer(), reencoded)
+	}
+}
+
+func Test_mixed_element_types(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		_, err := c.Marshal(general.List{Elements: []interface{}{int32(1), "two"}})
+		should.Error(err)
+		should.Contains(err.Error(), "expected element of type I32 but got String")
+		_, err = c.Marshal(general.List{ElementType: protocol.TypeI64, Elements: []interface{}{int32(1)}})
+		should.Error(err)
+		_, err = c.Marshal(general.Map{Entries: map[interface{}]interface{}{"a": int32(1), "b": int64(2)}})
+		should.Error(err)
+		_, err = c.Marshal(general.Map{Entries: map[interface{}]interface{}{"a": int32(1), int32(2): int32(2)}})
+		should.Error(err)
+	}
+}
+
+func Test_unsupported_element_types(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		_, err := c.Marshal(general.List{Elements: []interface{}{int32(1), 2}})
+		should.Error(err)
+		should.Contains(err.Error(), "unsupported element of type int")
+		_, err = c.Marshal(general.List{Elements: []interface{}{2}})
+		should.Error(err)
+		_, err = c.Marshal(general.List{Elements: []interface{}{int32(1), nil}})
+		should.Error(err)
+		should.Contains(err.Error(), "unsupported element of type nil")
+		_, err = c.Marshal(general.Map{Entries: map[interface{}]interface{}{"a": nil}})
+		should.Error(err)
+		_, err = c.Marshal(general.Struct{protocol.FieldId(1): general.List{Elements: []interface{}{nil}}})
+		should.Error(err)
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_equal(t *testing.T) {
+	should := require.New(t)
+	should.True(general.Equal(newOrder(), newOrder()))
+	should.True(general.Equal("abc", []byte("abc")))
+	should.False(general.Equal(int32(1), int64(1)))
+	other := newOrder()
+	other.Set(int32(3), protocol.FieldId(1), 1, protocol.FieldId(2))
+	should.False(general.Equal(newOrder(), other))
+	typed := general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(1)}}
+	inferred := general.List{Elements: []interface{}{int32(1)}}
+	should.True(general.Equal(typed, inferred))
+	should.False(general.Equal(general.List{ElementType: protocol.TypeString}, general.List{ElementType: protocol.TypeI32}))
+	key1 := general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(1)}}
+	key2 := general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(2)}}
+	should.True(general.Equal(
+		general.OrderedMap{Entries: []general.MapEntry{{Key: key1, Element: "a"}, {Key: key2, Element: "b"}}},
+		general.OrderedMap{Entries: []general.MapEntry{{Key: key2, Element: "b"}, {Key: key1, Element: "a"}}}))
+}
+
+func Test_clone(t *testing.T) {
+	should := require.New(t)
+	order := newOrder()
+	cloned := general.Clone(order).(general.Struct)
+	should.True(general.Equal(order, cloned))
+	should.NoError(cloned.Set("banana", protocol.FieldId(1), 0, protocol.FieldId(1)))
+	should.NoError(cloned.Set("note", protocol.FieldId(2), int32(8)))
+	should.Equal("apple", order.Get(protocol.FieldId(1), 0, protocol.FieldId(1)))
+	should.Len(order.Get(protocol.FieldId(2)).(general.Map).Entries, 1)
+	binary := []byte{1, 2}
+	clonedBinary := general.Clone(binary).([]byte)
+	clonedBinary[0] = 3
+	should.Equal(byte(1), binary[0])
+}
+
+func Test_diff(t *testing.T) {
+	should := require.New(t)
+	old := newOrder()
+	new := general.Clone(old).(general.Struct)
+	new.Set("kiwi", protocol.FieldId(1), 0, protocol.FieldId(1))
+	new.Delete(protocol.FieldId(1), 1)
+	new.Set("note", protocol.FieldId(2), int32(8))
+	new.Set(int64(1), protocol.FieldId(3))
+	diffs := general.Diff(old, new)
+	should.Equal([]general.Difference{
+		{Kind: general.Changed, Path: []interface{}{protocol.FieldId(1), 0, protocol.FieldId(1)},
+			OldType: protocol.TypeString, NewType: protocol.TypeString, Old: "apple", New: "kiwi"},
+		{Kind: general.Removed, Path: []interface{}{protocol.FieldId(1), 1},
+			OldType: protocol.TypeStruct, Old: old.Get(protocol.FieldId(1), 1)},
+		{Kind: general.Added, Path: []interface{}{protocol.FieldId(2), int32(8)},
+			NewType: protocol.TypeString, New: "note"},
+		{Kind: general.Added, Path: []interface{}{protocol.FieldId(3)},
+			NewType: protocol.TypeI64, New: int64(1)},
+	}, diffs)
+	should.Equal("~ 1.[0].1 String apple -> String kiwi", diffs[0].String())
+	should.Empty(general.Diff(old, general.Clone(old)))
+	typeChanged := general.Diff(general.Struct{protocol.FieldId(1): int32(1)}, general.Struct{protocol.FieldId(1): "1"})
+	should.Equal(1, len(typeChanged))
+	should.Equal(protocol.TypeI32, typeChanged[0].OldType)
+	should.Equal(protocol.TypeString, typeChanged[0].NewType)
+}
+
+func Test_diff_raw(t *testing.T) {
+	should := require.New(t)
+	old := newOrder()
+	old[protocol.FieldId(4)] = "unchanged"
+	new := general.Clone(old).(general.Struct)
+	new.Set(int32(3), protocol.FieldId(1), 1, protocol.FieldId(2))
+	new.Delete(protocol.FieldId(2))
+	new.Set(true, protocol.FieldId(5))
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		oldRaw, newRaw := toRaw(should, api, old), toRaw(should, api, new)
+		should.True(raw.Equal(oldRaw, raw.Clone(oldRaw)))
+		should.False(raw.Equal(oldRaw, newRaw))
+		added, removed, changed := raw.DiffFields(oldRaw, newRaw)
+		should.Equal([]protocol.FieldId{5}, added)
+		should.Equal([]protocol.FieldId{2}, removed)
+		should.Equal([]protocol.FieldId{1}, changed)
+		diffs, err := api.DiffRaw(oldRaw, newRaw)
+		should.NoError(err)
+		should.Equal(general.Diff(old, new), diffs)
+	}
+}
+
+func toRaw(should *require.Assertions, api thrifter.API, obj general.Struct) raw.Struct {
+	output, err := api.Marshal(obj)
+	should.NoError(err)
+	var val raw.Struct
+	should.NoError(api.Unmarshal(output, &val))
+	return val
+}
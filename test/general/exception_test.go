@@ -0,0 +1,46 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_application_exception(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.MarshalCombinations {
+		output, err := c.Marshal(protocol.NewApplicationException(protocol.ExceptionUnknownMethod, "unknown method foo"))
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal(general.Struct{
+			protocol.FieldId(1): "unknown method foo",
+			protocol.FieldId(2): int32(1),
+		}, val)
+		var exception protocol.ApplicationException
+		should.NoError(c.Unmarshal(output, &exception))
+		should.Equal(protocol.ExceptionUnknownMethod, exception.Type)
+		should.Equal("application exception UNKNOWN_METHOD: unknown method foo", exception.Error())
+	}
+}
+
+func Test_write_exception(t *testing.T) {
+	should := require.New(t)
+	for _, c := range test.Combinations {
+		stream := c.CreateStream()
+		request := protocol.MessageHeader{MessageName: "foo", MessageType: protocol.MessageTypeCall, SeqId: 3}
+		general.WriteException(stream, request, protocol.NewApplicationException(protocol.ExceptionInternalError, "oops"))
+		var msg general.Message
+		should.NoError(c.Unmarshal(stream.Buffer(), &msg))
+		should.Equal(general.Message{
+			MessageHeader: protocol.MessageHeader{
+				MessageName: "foo", MessageType: protocol.MessageTypeException, SeqId: 3},
+			Arguments: general.Struct{
+				protocol.FieldId(1): "oops",
+				protocol.FieldId(2): int32(6),
+			},
+		}, msg)
+	}
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"errors"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newRequest() general.Struct {
+	return general.Struct{
+		protocol.FieldId(1): newOrder(),
+		protocol.FieldId(2): general.Struct{
+			protocol.FieldId(1): general.Map{KeyType: protocol.TypeString, ElementType: protocol.TypeString,
+				Entries: map[interface{}]interface{}{"tenant": "acme", "region": "eu"}},
+		},
+		protocol.FieldId(3): general.List{ElementType: protocol.TypeI64, Elements: []interface{}{int64(1), int64(2)}},
+	}
+}
+
+func Test_get_from_bytes(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		output, err := api.Marshal(newRequest())
+		should.NoError(err)
+		val, err := api.Get(output, protocol.FieldId(2), protocol.FieldId(1), "tenant")
+		should.NoError(err)
+		should.Equal("acme", val)
+		val, err = api.Get(output, protocol.FieldId(1), protocol.FieldId(1), 1, protocol.FieldId(1))
+		should.NoError(err)
+		should.Equal("orange", val)
+		val, err = api.Get(output, protocol.FieldId(1), protocol.FieldId(2), int32(7))
+		should.NoError(err)
+		should.Equal("gift", val)
+		val, err = api.Get(output, protocol.FieldId(3))
+		should.NoError(err)
+		should.Equal(newRequest()[protocol.FieldId(3)], val)
+		val, err = api.Get(output)
+		should.NoError(err)
+		should.True(general.Equal(newRequest(), val))
+	}
+}
+
+func Test_get_from_message(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		output, err := api.Marshal(general.Message{
+			MessageHeader: protocol.MessageHeader{MessageName: "route", MessageType: protocol.MessageTypeCall},
+			Arguments:     newRequest(),
+		})
+		should.NoError(err)
+		path, err := general.ParsePath("2.1.tenant")
+		should.NoError(err)
+		val, err := api.GetMessage(output, path...)
+		should.NoError(err)
+		should.Equal("acme", val)
+		path, err = general.ParsePath("1/1/[0]/1")
+		should.NoError(err)
+		val, err = api.GetMessage(output, path...)
+		should.NoError(err)
+		should.Equal("apple", val)
+		path, err = general.ParsePath("1.2.7")
+		should.NoError(err)
+		val, err = api.GetMessage(output, path...)
+		should.NoError(err)
+		should.Equal("gift", val)
+	}
+}
+
+func Test_get_missing_path(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		output, err := api.Marshal(newRequest())
+		should.NoError(err)
+		_, err = api.Get(output, protocol.FieldId(9))
+		should.True(errors.Is(err, general.ErrNotFound))
+		_, err = api.Get(output, protocol.FieldId(3), 2)
+		should.True(errors.Is(err, general.ErrNotFound))
+		should.Contains(err.Error(), "3.[2]")
+		_, err = api.Get(output, protocol.FieldId(2), protocol.FieldId(1), "user")
+		should.True(errors.Is(err, general.ErrNotFound))
+		_, err = api.Get(output, protocol.FieldId(3), 0, 0)
+		should.Error(err)
+		_, err = api.Get(output, "tenant")
+		should.Error(err)
+		_, err = api.Get(output[:len(output)-2], protocol.FieldId(9))
+		should.Error(err)
+	}
+}
+
+func Test_get_struct_like_message_header(t *testing.T) {
+	should := require.New(t)
+	// compact field 8 of bool false starts with 0x82, same as compact message header
+	api := thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze()
+	output, err := api.Marshal(general.Struct{protocol.FieldId(8): false})
+	should.NoError(err)
+	should.Equal(byte(protocol.COMPACT_PROTOCOL_ID), output[0])
+	val, err := api.Get(output, protocol.FieldId(8))
+	should.NoError(err)
+	should.Equal(false, val)
+	// field 10 of bool true follows as 0x21, which also has the compact version in low bits
+	val, err = api.Get([]byte{0x82, 0x21, 0x00}, protocol.FieldId(10))
+	should.NoError(err)
+	should.Equal(true, val)
+}
+
+func Benchmark_get_from_bytes(b *testing.B) {
+	output, _ := thrifter.Marshal(newRequest())
+	b.ReportAllocs()
+	b.Run("unmarshal", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var val general.Struct
+			thrifter.Unmarshal(output, &val)
+			_ = val.Get(protocol.FieldId(2), protocol.FieldId(1), "tenant").(string)
+		}
+	})
+	b.Run("get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			val, _ := thrifter.Get(output, protocol.FieldId(2), protocol.FieldId(1), "tenant")
+			_ = val.(string)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"errors"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type patchedRequest struct {
+	Trace   string           `thrift:",3"`
+	Tenant  string           `thrift:",1"`
+	Enabled bool             `thrift:",2"`
+	Tags    []string         `thrift:",4"`
+	Quota   map[string]int32 `thrift:",5"`
+}
+
+func Test_patch_keeps_untouched_bytes(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		original := patchedRequest{Trace: "t1", Tenant: "acme", Enabled: true,
+			Tags: []string{"a", "b"}, Quota: map[string]int32{"cpu": 1}}
+		output, err := api.Marshal(original)
+		should.NoError(err)
+		patched, err := api.Set(output, "globex", protocol.FieldId(1))
+		should.NoError(err)
+		expected := original
+		expected.Tenant = "globex"
+		expectedOutput, err := api.Marshal(expected)
+		should.NoError(err)
+		should.Equal(expectedOutput, patched)
+		patched, err = api.Set(output, false, protocol.FieldId(2))
+		should.NoError(err)
+		expected = original
+		expected.Enabled = false
+		expectedOutput, err = api.Marshal(expected)
+		should.NoError(err)
+		should.Equal(expectedOutput, patched)
+		patched, err = api.Set(output, "c", protocol.FieldId(4), 2)
+		should.NoError(err)
+		expected = original
+		expected.Tags = []string{"a", "b", "c"}
+		expectedOutput, err = api.Marshal(expected)
+		should.NoError(err)
+		should.Equal(expectedOutput, patched)
+		patched, err = api.Delete(output, protocol.FieldId(4), 0)
+		should.NoError(err)
+		expected = original
+		expected.Tags = []string{"b"}
+		expectedOutput, err = api.Marshal(expected)
+		should.NoError(err)
+		should.Equal(expectedOutput, patched)
+		patched, err = api.Set(output, int32(2), protocol.FieldId(5), "cpu")
+		should.NoError(err)
+		expected = original
+		expected.Quota = map[string]int32{"cpu": 2}
+		expectedOutput, err = api.Marshal(expected)
+		should.NoError(err)
+		should.Equal(expectedOutput, patched)
+	}
+}
+
+func Test_patch_general_paths(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		output, err := api.Marshal(newRequest())
+		should.NoError(err)
+		expected := newRequest()
+		patched := output
+		patch := func(value interface{}, path ...interface{}) {
+			patched, err = api.Set(patched, value, path...)
+			should.NoError(err)
+			should.NoError(expected.Set(value, path...))
+		}
+		remove := func(path ...interface{}) {
+			patched, err = api.Delete(patched, path...)
+			should.NoError(err)
+			should.NoError(expected.Delete(path...))
+		}
+		patch("globex", protocol.FieldId(2), protocol.FieldId(1), "tenant")
+		patch("t-1", protocol.FieldId(2), protocol.FieldId(1), "trace")
+		patch("trace-1", protocol.FieldId(4), protocol.FieldId(1), protocol.FieldId(2))
+		patch(int32(9), protocol.FieldId(1), protocol.FieldId(1), 1, protocol.FieldId(2))
+		patch(general.Struct{protocol.FieldId(1): "pear"}, protocol.FieldId(1), protocol.FieldId(1), 2)
+		remove(protocol.FieldId(1), protocol.FieldId(1), 0)
+		remove(protocol.FieldId(2), protocol.FieldId(1), "region")
+		remove(protocol.FieldId(3))
+		var val general.Struct
+		should.NoError(api.Unmarshal(patched, &val))
+		should.Empty(general.Diff(expected, val))
+	}
+}
+
+func Test_patch_message(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		header := protocol.MessageHeader{MessageName: "route", MessageType: protocol.MessageTypeCall, SeqId: 7}
+		output, err := api.Marshal(general.Message{MessageHeader: header, Arguments: newRequest()})
+		should.NoError(err)
+		path, err := general.ParsePath("2.1.trace")
+		should.NoError(err)
+		patched, err := api.SetMessage(output, "t-1", path...)
+		should.NoError(err)
+		msg, err := api.UnmarshalMessage(patched)
+		should.NoError(err)
+		should.Equal(header, msg.MessageHeader)
+		trace, err := msg.Arguments.GetE(path...)
+		should.NoError(err)
+		should.Equal("t-1", trace)
+		should.Equal("acme", msg.Arguments.Get(protocol.FieldId(2), protocol.FieldId(1), "tenant"))
+		patched, err = api.DeleteMessage(patched, protocol.FieldId(3))
+		should.NoError(err)
+		msg, err = api.UnmarshalMessage(patched)
+		should.NoError(err)
+		should.Equal(header, msg.MessageHeader)
+		should.NotContains(msg.Arguments, protocol.FieldId(3))
+	}
+}
+
+func Test_patch_struct_like_message_header(t *testing.T) {
+	should := require.New(t)
+	api := thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze()
+	// compact bool fields 8 and 10, starting like a compact message header of version 1
+	output, err := api.Set([]byte{0x82, 0x21, 0x00}, false, protocol.FieldId(10))
+	should.NoError(err)
+	var val general.Struct
+	should.NoError(api.Unmarshal(output, &val))
+	should.Equal(general.Struct{protocol.FieldId(8): false, protocol.FieldId(10): false}, val)
+}
+
+func Test_patch_empty_containers(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		output, err := api.Marshal(general.Struct{
+			protocol.FieldId(1): general.List{ElementType: protocol.TypeString},
+			protocol.FieldId(2): general.Map{KeyType: protocol.TypeString, ElementType: protocol.TypeI32},
+		})
+		should.NoError(err)
+		output, err = api.Set(output, "a", protocol.FieldId(1), 0)
+		should.NoError(err)
+		output, err = api.Set(output, int32(1), protocol.FieldId(2), "a")
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(api.Unmarshal(output, &val))
+		should.Equal("a", val.Get(protocol.FieldId(1), 0))
+		should.Equal(int32(1), val.Get(protocol.FieldId(2), "a"))
+		output, err = api.Delete(output, protocol.FieldId(2), "a")
+		should.NoError(err)
+		val = nil
+		should.NoError(api.Unmarshal(output, &val))
+		should.Len(val.Get(protocol.FieldId(2)).(general.Map).Entries, 0)
+	}
+}
+
+func Test_patch_errors(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		output, err := api.Marshal(newRequest())
+		should.NoError(err)
+		_, err = api.Delete(output, protocol.FieldId(9))
+		should.True(errors.Is(err, general.ErrNotFound))
+		_, err = api.Delete(output, protocol.FieldId(2), protocol.FieldId(1), "user")
+		should.True(errors.Is(err, general.ErrNotFound))
+		_, err = api.Set(output, int64(1), protocol.FieldId(3), 5)
+		should.True(errors.Is(err, general.ErrNotFound))
+		_, err = api.Set(output, "x", protocol.FieldId(3), 0)
+		should.Error(err)
+		should.Contains(err.Error(), "3.[0]")
+		_, err = api.Set(output, "x", protocol.FieldId(3), 0, 0)
+		should.Error(err)
+		_, err = api.Set(output[:len(output)-2], "x", protocol.FieldId(9))
+		should.Error(err)
+		_, err = api.Set(output, nil, protocol.FieldId(1))
+		should.Error(err)
+	}
+}
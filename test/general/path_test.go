@@ -0,0 +1,147 @@
+package test
+
+import (
+	"errors"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newOrder() general.Struct {
+	return general.Struct{
+		protocol.FieldId(1): general.List{ElementType: protocol.TypeStruct, Elements: []interface{}{
+			general.Struct{protocol.FieldId(1): "apple", protocol.FieldId(2): int32(1)},
+			general.Struct{protocol.FieldId(1): "orange", protocol.FieldId(2): int32(2)},
+		}},
+		protocol.FieldId(2): general.Map{KeyType: protocol.TypeI32, ElementType: protocol.TypeString,
+			Entries: map[interface{}]interface{}{int32(7): "gift"}},
+	}
+}
+
+func Test_get_with_error(t *testing.T) {
+	should := require.New(t)
+	order := newOrder()
+	val, err := order.GetE(protocol.FieldId(1), 1, protocol.FieldId(1))
+	should.NoError(err)
+	should.Equal("orange", val)
+	_, err = order.GetE(protocol.FieldId(1), 2)
+	should.True(errors.Is(err, general.ErrNotFound))
+	should.Contains(err.Error(), "1.[2]")
+	_, err = order.GetE(protocol.FieldId(1), protocol.FieldId(1))
+	should.Error(err)
+	_, err = order.GetE(protocol.FieldId(1), 0, protocol.FieldId(1), 0)
+	should.Error(err)
+}
+
+func Test_parse_path(t *testing.T) {
+	should := require.New(t)
+	order := newOrder()
+	for _, path := range []string{"1.1.1", "1/[1]/1", "1.[1].1"} {
+		parsed, err := general.ParsePath(path)
+		should.NoError(err)
+		val, err := order.GetE(parsed...)
+		should.NoError(err)
+		should.Equal("orange", val)
+	}
+	parsed, err := general.ParsePath("2.7")
+	should.NoError(err)
+	val, err := order.GetE(parsed...)
+	should.NoError(err)
+	should.Equal("gift", val)
+	_, err = general.ParsePath("1.[x]")
+	should.Error(err)
+	parsed, err = general.ParsePath("x.1")
+	should.NoError(err)
+	_, err = order.GetE(parsed...)
+	should.Error(err)
+}
+
+func Test_set_creates_intermediate_structs(t *testing.T) {
+	should := require.New(t)
+	order := newOrder()
+	should.NoError(order.Set("bob", protocol.FieldId(3), protocol.FieldId(1), protocol.FieldId(2)))
+	should.Equal("bob", order.Get(protocol.FieldId(3), protocol.FieldId(1), protocol.FieldId(2)))
+	should.NoError(order.Set(int32(5), protocol.FieldId(1), 0, protocol.FieldId(2)))
+	should.Equal(int32(5), order.Get(protocol.FieldId(1), 0, protocol.FieldId(2)))
+	should.NoError(order.Set(general.Struct{protocol.FieldId(1): "pear"}, protocol.FieldId(1), 2))
+	should.Equal("pear", order.Get(protocol.FieldId(1), 2, protocol.FieldId(1)))
+	should.Error(order.Set("x", protocol.FieldId(1), 5))
+	should.NoError(order.Set("wrap", protocol.FieldId(2), int32(8)))
+	should.Equal("wrap", order.Get(protocol.FieldId(2), int32(8)))
+	for _, c := range test.Combinations {
+		output, err := c.Marshal(order)
+		should.NoError(err)
+		var val general.Struct
+		should.NoError(c.Unmarshal(output, &val))
+		should.Equal("bob", val.Get(protocol.FieldId(3), protocol.FieldId(1), protocol.FieldId(2)))
+		should.Equal("pear", val.Get(protocol.FieldId(1), 2, protocol.FieldId(1)))
+	}
+}
+
+func Test_delete(t *testing.T) {
+	should := require.New(t)
+	order := newOrder()
+	should.NoError(order.Delete(protocol.FieldId(1), 0))
+	should.Equal("orange", order.Get(protocol.FieldId(1), 0, protocol.FieldId(1)))
+	should.NoError(order.Delete(protocol.FieldId(2), int32(7)))
+	should.Len(order.Get(protocol.FieldId(2)).(general.Map).Entries, 0)
+	err := order.Delete(protocol.FieldId(9))
+	should.True(errors.Is(err, general.ErrNotFound))
+	should.NoError(order.Delete(protocol.FieldId(2)))
+	_, err = order.GetE(protocol.FieldId(2))
+	should.True(errors.Is(err, general.ErrNotFound))
+}
+
+func Test_set_on_containers(t *testing.T) {
+	should := require.New(t)
+	list := general.List{ElementType: protocol.TypeString}
+	should.NoError(list.Set("a", 0))
+	should.NoError(list.Set("b", 1))
+	should.NoError(list.Delete(0))
+	should.Equal([]interface{}{"b"}, list.Elements)
+	var scores general.Map
+	should.NoError(scores.Set(int32(1), "a"))
+	should.Equal(int32(1), scores.Get("a"))
+	ordered := general.OrderedMap{}
+	key := general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(1)}}
+	should.NoError(ordered.Set("one", key))
+	should.Equal("one", ordered.Get(key))
+	should.NoError(ordered.Delete(key))
+	should.Len(ordered.Entries, 0)
+}
+
+func Test_ordered_map_key_built_by_hand(t *testing.T) {
+	should := require.New(t)
+	output, err := thrifter.Marshal(general.Struct{protocol.FieldId(1): general.OrderedMap{Entries: []general.MapEntry{
+		{Key: general.List{ElementType: protocol.TypeI32, Elements: []interface{}{int32(1)}}, Element: "one"},
+	}}})
+	should.NoError(err)
+	var obj general.Struct
+	should.NoError(thrifter.Unmarshal(output, &obj))
+	// element type is not set, same as the key read from bytes by Equal
+	key := general.List{Elements: []interface{}{int32(1)}}
+	val, err := thrifter.Get(output, protocol.FieldId(1), key)
+	should.NoError(err)
+	should.Equal("one", val)
+	val, err = obj.GetE(protocol.FieldId(1), key)
+	should.NoError(err)
+	should.Equal("one", val)
+	should.NoError(obj.Set("uno", protocol.FieldId(1), key))
+	should.Len(obj.Get(protocol.FieldId(1)).(general.OrderedMap).Entries, 1)
+	should.NoError(obj.Delete(protocol.FieldId(1), key))
+	should.Len(obj.Get(protocol.FieldId(1)).(general.OrderedMap).Entries, 0)
+}
+
+func Test_set_message_arguments(t *testing.T) {
+	should := require.New(t)
+	msg := general.Message{Arguments: newOrder()}
+	path, err := general.ParsePath("1/[0]/1")
+	should.NoError(err)
+	should.NoError(msg.Arguments.Set("kiwi", path...))
+	val, err := msg.Arguments.GetE(path...)
+	should.NoError(err)
+	should.Equal("kiwi", val)
+}
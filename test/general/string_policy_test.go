@@ -0,0 +1,72 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Envelope struct {
+	Payload interface{} `thrift:"payload,1"`
+}
+
+var protocols = []thrifter.Protocol{thrifter.ProtocolBinary, thrifter.ProtocolCompact}
+
+func Test_string_policy(t *testing.T) {
+	should := require.New(t)
+	invalid := []byte{0xff, 0xfe}
+	for _, proto := range protocols {
+		for policy, expected := range map[spi.StringPolicy][]interface{}{
+			spi.StringAlways:        {"hello", string(invalid)},
+			spi.BinaryAlways:        {[]byte("hello"), invalid},
+			spi.BinaryIfInvalidUTF8: {"hello", invalid},
+		} {
+			api := thrifter.Config{Protocol: proto, StringPolicy: policy}.Froze()
+			output, err := api.Marshal(general.List{Elements: []interface{}{"hello", invalid}})
+			should.NoError(err)
+			var val general.List
+			should.NoError(api.Unmarshal(output, &val))
+			should.Equal(expected, val.Elements)
+			reencoded, err := api.Marshal(val)
+			should.NoError(err)
+			should.Equal(output, reencoded)
+			output, err = api.Marshal(Envelope{Payload: invalid})
+			should.NoError(err)
+			var envelope Envelope
+			should.NoError(api.Unmarshal(output, &envelope))
+			should.Equal(expected[1], envelope.Payload)
+		}
+	}
+}
+
+func Test_binary_map_keys_stay_string(t *testing.T) {
+	should := require.New(t)
+	api := thrifter.Config{Protocol: thrifter.ProtocolBinary, StringPolicy: spi.BinaryAlways}.Froze()
+	output, err := api.Marshal(map[string]string{"key": "elem"})
+	should.NoError(err)
+	var val general.Map
+	should.NoError(api.Unmarshal(output, &val))
+	should.Equal(map[interface{}]interface{}{"key": []byte("elem")}, val.Entries)
+}
+
+func Test_to_json_encodes_binary_as_base64(t *testing.T) {
+	should := require.New(t)
+	for _, proto := range protocols {
+		api := thrifter.Config{Protocol: proto}.Froze()
+		output, err := api.Marshal(general.Message{
+			MessageHeader: protocol.MessageHeader{MessageName: "hello", MessageType: protocol.MessageTypeCall},
+			Arguments: general.Struct{
+				protocol.FieldId(1): "world",
+				protocol.FieldId(2): []byte{0xff, 0xfe},
+			},
+		})
+		should.NoError(err)
+		jsonEncoded, err := api.ToJSON(output)
+		should.NoError(err)
+		should.Contains(jsonEncoded, `"world"`)
+		should.Contains(jsonEncoded, `"//4="`)
+	}
+}
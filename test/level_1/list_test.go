@@ -72,7 +72,10 @@ func Test_unmarshal_general_list(t *testing.T) {
 		proto.WriteListEnd()
 		var val general.List
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
-		should.Equal(general.List{int64(1), int64(2), int64(3)}, val)
+		should.Equal(general.List{
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1), int64(2), int64(3)},
+		}, val)
 	}
 }
 
@@ -113,7 +116,7 @@ func Test_marshal_general_list(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
 		output, err := c.Marshal(general.List{
-			int64(1), int64(2), int64(3),
+			Elements: []interface{}{int64(1), int64(2), int64(3)},
 		})
 		should.NoError(err)
 		iter := c.CreateIterator(output)
@@ -141,7 +144,10 @@ func Test_marshal_raw_list(t *testing.T) {
 		should.NoError(err)
 		var generalVal general.List
 		should.NoError(c.Unmarshal(output, &generalVal))
-		should.Equal(general.List{int64(1), int64(2), int64(3)}, generalVal)
+		should.Equal(general.List{
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1), int64(2), int64(3)},
+		}, generalVal)
 	}
 }
 
@@ -93,9 +93,13 @@ func Test_unmarshal_general_map(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.Map{
-			int32(1): int64(1),
-			int32(2): int64(2),
-			int32(3): int64(3),
+			KeyType:     protocol.TypeI32,
+			ElementType: protocol.TypeI64,
+			Entries: map[interface{}]interface{}{
+				int32(1): int64(1),
+				int32(2): int64(2),
+				int32(3): int64(3),
+			},
 		}, val)
 	}
 }
@@ -147,11 +151,11 @@ func Test_unmarshal_map(t *testing.T) {
 func Test_marshal_general_map(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		m := general.Map{
+		m := general.Map{Entries: map[interface{}]interface{}{
 			int32(1): int64(1),
 			int32(2): int64(2),
 			int32(3): int64(3),
-		}
+		}}
 
 		output, err := c.Marshal(m)
 		should.NoError(err)
@@ -162,9 +166,13 @@ func Test_marshal_general_map(t *testing.T) {
 		should.NoError(c.Unmarshal(output1, &val1))
 		should.Equal(val, val1)
 		should.Equal(general.Map{
-			int32(1): int64(1),
-			int32(2): int64(2),
-			int32(3): int64(3),
+			KeyType:     protocol.TypeI32,
+			ElementType: protocol.TypeI64,
+			Entries: map[interface{}]interface{}{
+				int32(1): int64(1),
+				int32(2): int64(2),
+				int32(3): int64(3),
+			},
 		}, val)
 	}
 }
@@ -193,9 +201,13 @@ func Test_marshal_raw_map(t *testing.T) {
 		should.NoError(c.Unmarshal(output1, &generalVal1))
 		should.Equal(generalVal, generalVal1)
 		should.Equal(general.Map{
-			int32(1): int64(1),
-			int32(2): int64(2),
-			int32(3): int64(3),
+			KeyType:     protocol.TypeI32,
+			ElementType: protocol.TypeI64,
+			Entries: map[interface{}]interface{}{
+				int32(1): int64(1),
+				int32(2): int64(2),
+				int32(3): int64(3),
+			},
 		}, generalVal)
 	}
 }
@@ -218,9 +230,13 @@ func Test_marshal_map(t *testing.T) {
 		should.NoError(c.Unmarshal(output1, &val1))
 		should.Equal(val, val1)
 		should.Equal(general.Map{
-			"k1": int64(1),
-			"k2": int64(2),
-			"k3": int64(3),
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries: map[interface{}]interface{}{
+				"k1": int64(1),
+				"k2": int64(2),
+				"k3": int64(3),
+			},
 		}, val)
 	}
 }
@@ -232,6 +248,10 @@ func Test_marshal_empty_map(t *testing.T) {
 		should.NoError(err)
 		var val general.Map
 		should.NoError(c.Unmarshal(output, &val))
-		should.Equal(general.Map{}, val)
+		should.Equal(general.Map{
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{},
+		}, val)
 	}
 }
@@ -3,6 +3,7 @@ package test
 import (
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/test"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -39,7 +40,7 @@ func Test_unmarshal_general_list_of_list(t *testing.T) {
 		proto.WriteListEnd()
 		var val general.List
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
-		should.Equal(general.List{int64(1)}, val[0])
+		should.Equal(general.List{ElementType: protocol.TypeI64, Elements: []interface{}{int64(1)}}, val.Elements[0])
 	}
 }
 
@@ -57,7 +58,7 @@ func Test_unmarshal_list_of_general_list(t *testing.T) {
 		proto.WriteListEnd()
 		var val []general.List
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
-		should.Equal(general.List{int64(1)}, val[0])
+		should.Equal(general.List{ElementType: protocol.TypeI64, Elements: []interface{}{int64(1)}}, val[0])
 	}
 }
 
@@ -84,14 +85,14 @@ func Test_unmarshal_list_of_list(t *testing.T) {
 func Test_marshal_general_list_of_list(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		lst := general.List{
-			general.List{
+		lst := general.List{Elements: []interface{}{
+			general.List{Elements: []interface{}{
 				int64(1),
-			},
-			general.List{
+			}},
+			general.List{Elements: []interface{}{
 				int64(2),
-			},
-		}
+			}},
+		}}
 
 		output, err := c.Marshal(lst)
 		should.NoError(err)
@@ -100,7 +101,7 @@ func Test_marshal_general_list_of_list(t *testing.T) {
 		should.Equal(output, output1)
 		var val general.List
 		should.NoError(c.Unmarshal(output, &val))
-		should.Equal(general.List{int64(1)}, val[0])
+		should.Equal(general.List{ElementType: protocol.TypeI64, Elements: []interface{}{int64(1)}}, val.Elements[0])
 	}
 }
 
@@ -108,12 +109,12 @@ func Test_marshal_list_of_general_list(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.MarshalCombinations {
 		lst := []general.List{
-			{
+			{Elements: []interface{}{
 				int64(1),
-			},
-			{
+			}},
+			{Elements: []interface{}{
 				int64(2),
-			},
+			}},
 		}
 
 		output, err := c.Marshal(lst)
@@ -123,7 +124,7 @@ func Test_marshal_list_of_general_list(t *testing.T) {
 		should.Equal(output, output1)
 		var val general.List
 		should.NoError(c.Unmarshal(output, &val))
-		should.Equal(general.List{int64(1)}, val[0])
+		should.Equal(general.List{ElementType: protocol.TypeI64, Elements: []interface{}{int64(1)}}, val.Elements[0])
 	}
 }
 
@@ -141,6 +142,6 @@ func Test_marshal_list_of_list(t *testing.T) {
 		should.Equal(output, output1)
 		var val general.List
 		should.NoError(c.Unmarshal(output, &val))
-		should.Equal(general.List{int64(1)}, val[0])
+		should.Equal(general.List{ElementType: protocol.TypeI64, Elements: []interface{}{int64(1)}}, val.Elements[0])
 	}
 }
@@ -3,6 +3,7 @@ package test
 import (
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/test"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -44,8 +45,10 @@ func Test_unmarshal_general_list_of_map(t *testing.T) {
 		var val general.List
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.Map{
-			int32(1): int64(1),
-		}, val[0])
+			KeyType:     protocol.TypeI32,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{int32(1): int64(1)},
+		}, val.Elements[0])
 		should.Equal(int64(1), val.Get(0, int32(1)))
 	}
 }
@@ -75,14 +78,14 @@ func Test_unmarshal_list_of_map(t *testing.T) {
 func Test_marshal_general_list_of_map(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		lst := general.List{
-			general.Map{
+		lst := general.List{Elements: []interface{}{
+			general.Map{Entries: map[interface{}]interface{}{
 				int32(1): int64(1),
-			},
-			general.Map{
+			}},
+			general.Map{Entries: map[interface{}]interface{}{
 				int32(2): int64(2),
-			},
-		}
+			}},
+		}}
 
 		output, err := c.Marshal(lst)
 		should.NoError(err)
@@ -3,6 +3,7 @@ package test
 import (
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/test"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -33,7 +34,10 @@ func Test_unmarshal_general_list_of_string(t *testing.T) {
 		proto.WriteListEnd()
 		var val general.List
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
-		should.Equal(general.List{"a", "b", "c"}, val)
+		should.Equal(general.List{
+			ElementType: protocol.TypeString,
+			Elements:    []interface{}{"a", "b", "c"},
+		}, val)
 	}
 }
 
@@ -57,9 +61,9 @@ func Test_unmarshal_list_of_string(t *testing.T) {
 func Test_marshal_general_list_of_string(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		lst := general.List{
+		lst := general.List{Elements: []interface{}{
 			"a", "b", "c",
-		}
+		}}
 
 		output, err := c.Marshal(lst)
 		should.NoError(err)
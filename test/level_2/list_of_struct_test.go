@@ -55,7 +55,7 @@ func Test_unmarshal_general_list_of_struct(t *testing.T) {
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[0])
+		}, val.Elements[0])
 	}
 }
 
@@ -88,14 +88,14 @@ func Test_unmarshal_list_of_struct(t *testing.T) {
 func Test_marshal_general_list_of_struct(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		lst := general.List{
+		lst := general.List{Elements: []interface{}{
 			general.Struct{
 				protocol.FieldId(1): int64(1024),
 			},
 			general.Struct{
 				protocol.FieldId(1): int64(1024),
 			},
-		}
+		}}
 
 		output, err := c.Marshal(lst)
 		should.NoError(err)
@@ -106,10 +106,10 @@ func Test_marshal_general_list_of_struct(t *testing.T) {
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[0])
+		}, val.Elements[0])
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[1])
+		}, val.Elements[1])
 	}
 }
 
@@ -129,9 +129,9 @@ func Test_marshal_list_of_struct(t *testing.T) {
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[0])
+		}, val.Elements[0])
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[1])
+		}, val.Elements[1])
 	}
 }
@@ -3,6 +3,7 @@ package test
 import (
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/test"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -36,8 +37,9 @@ func Test_unmarshal_general_map_of_list(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.List{
-			int64(1),
-		}, val[int64(1)])
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1)},
+		}, val.Entries[int64(1)])
 	}
 }
 
@@ -62,9 +64,9 @@ func Test_unmarshal_map_of_list(t *testing.T) {
 func Test_marshal_general_map_of_list(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		m := general.Map{
-			int64(1): general.List{int64(1)},
-		}
+		m := general.Map{Entries: map[interface{}]interface{}{
+			int64(1): general.List{Elements: []interface{}{int64(1)}},
+		}}
 
 		output, err := c.Marshal(m)
 		should.NoError(err)
@@ -74,8 +76,9 @@ func Test_marshal_general_map_of_list(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.List{
-			int64(1),
-		}, val[int64(1)])
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1)},
+		}, val.Entries[int64(1)])
 	}
 }
 
@@ -94,7 +97,8 @@ func Test_marshal_map_of_list(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.List{
-			int64(1),
-		}, val[int64(1)])
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1)},
+		}, val.Entries[int64(1)])
 	}
 }
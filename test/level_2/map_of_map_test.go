@@ -3,6 +3,7 @@ package test
 import (
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/test"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -42,19 +43,21 @@ func Test_unmarshal_general_map_of_map(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.Map{
-			"k1": int64(1),
-		}, val[int64(1)])
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{"k1": int64(1)},
+		}, val.Entries[int64(1)])
 	}
 }
 
 func Test_marshal_general_map_of_map(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		m := general.Map{
-			int64(1): general.Map{
+		m := general.Map{Entries: map[interface{}]interface{}{
+			int64(1): general.Map{Entries: map[interface{}]interface{}{
 				"k1": int64(1),
-			},
-		}
+			}},
+		}}
 
 		output, err := c.Marshal(m)
 		should.NoError(err)
@@ -64,8 +67,10 @@ func Test_marshal_general_map_of_map(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Map{
-			"k1": int64(1),
-		}, val[int64(1)])
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{"k1": int64(1)},
+		}, val.Entries[int64(1)])
 	}
 }
 
@@ -84,7 +89,9 @@ func Test_marshal_map_of_map(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Map{
-			"k1": int64(1),
-		}, val[int64(1)])
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{"k1": int64(1)},
+		}, val.Entries[int64(1)])
 	}
 }
@@ -3,6 +3,7 @@ package test
 import (
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
 	"github.com/batchcorp/thrift-iterator/test"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -45,7 +46,9 @@ func Test_unmarshal_general_map_of_string_key(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.Map{
-			"1": int64(1),
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{"1": int64(1)},
 		}, val)
 	}
 }
@@ -69,9 +72,9 @@ func Test_unmarshal_map_of_string_key(t *testing.T) {
 func Test_marshal_general_map_of_string_key(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		m := general.Map{
+		m := general.Map{Entries: map[interface{}]interface{}{
 			"1": int64(1),
-		}
+		}}
 
 		output, err := c.Marshal(m)
 		should.NoError(err)
@@ -81,7 +84,9 @@ func Test_marshal_general_map_of_string_key(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Map{
-			"1": int64(1),
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{"1": int64(1)},
 		}, val)
 	}
 }
@@ -101,7 +106,9 @@ func Test_marshal_map_of_string_key(t *testing.T) {
 		var val general.Map
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Map{
-			"1": int64(1),
+			KeyType:     protocol.TypeString,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{"1": int64(1)},
 		}, val)
 	}
 }
@@ -49,7 +49,7 @@ func Test_unmarshal_general_map_of_struct(t *testing.T) {
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[int64(1)])
+		}, val.Entries[int64(1)])
 	}
 }
 
@@ -79,11 +79,11 @@ func Test_unmarshal_map_of_struct(t *testing.T) {
 func Test_marshal_general_map_of_struct(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
-		m := general.Map{
+		m := general.Map{Entries: map[interface{}]interface{}{
 			int64(1): general.Struct{
 				protocol.FieldId(1): int64(1024),
 			},
-		}
+		}}
 
 		output, err := c.Marshal(m)
 		should.NoError(err)
@@ -94,7 +94,7 @@ func Test_marshal_general_map_of_struct(t *testing.T) {
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[int64(1)])
+		}, val.Entries[int64(1)])
 	}
 }
 
@@ -114,6 +114,6 @@ func Test_marshal_map_of_struct(t *testing.T) {
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Struct{
 			protocol.FieldId(1): int64(1024),
-		}, val[int64(1)])
+		}, val.Entries[int64(1)])
 	}
 }
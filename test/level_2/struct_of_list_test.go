@@ -41,7 +41,10 @@ func Test_unmarshal_general_struct_of_list(t *testing.T) {
 		proto.WriteStructEnd()
 		var val general.Struct
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
-		should.Equal(general.List{int64(1)}, val[protocol.FieldId(1)])
+		should.Equal(general.List{
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1)},
+		}, val[protocol.FieldId(1)])
 	}
 }
 
@@ -69,9 +72,9 @@ func Test_marshal_general_struct_of_list(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
 		obj := general.Struct{
-			protocol.FieldId(1): general.List{
+			protocol.FieldId(1): general.List{Elements: []interface{}{
 				int64(1),
-			},
+			}},
 		}
 
 		output, err := c.Marshal(obj)
@@ -81,7 +84,10 @@ func Test_marshal_general_struct_of_list(t *testing.T) {
 		should.Equal(output, output1)
 		var val general.Struct
 		should.NoError(c.Unmarshal(output, &val))
-		should.Equal(general.List{int64(1)}, val[protocol.FieldId(1)])
+		should.Equal(general.List{
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1)},
+		}, val[protocol.FieldId(1)])
 	}
 }
 
@@ -99,6 +105,9 @@ func Test_marshal_struct_of_list(t *testing.T) {
 		should.Equal(output, output1)
 		var val general.Struct
 		should.NoError(c.Unmarshal(output, &val))
-		should.Equal(general.List{int64(1)}, val[protocol.FieldId(1)])
+		should.Equal(general.List{
+			ElementType: protocol.TypeI64,
+			Elements:    []interface{}{int64(1)},
+		}, val[protocol.FieldId(1)])
 	}
 }
@@ -44,7 +44,9 @@ func Test_unmarshal_general_struct_of_map(t *testing.T) {
 		var val general.Struct
 		should.NoError(c.Unmarshal(buf.Bytes(), &val))
 		should.Equal(general.Map{
-			int32(2): int64(2),
+			KeyType:     protocol.TypeI32,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{int32(2): int64(2)},
 		}, val[protocol.FieldId(1)])
 	}
 }
@@ -74,9 +76,9 @@ func Test_marshal_general_struct_of_map(t *testing.T) {
 	should := require.New(t)
 	for _, c := range test.Combinations {
 		m := general.Struct{
-			protocol.FieldId(1): general.Map{
+			protocol.FieldId(1): general.Map{Entries: map[interface{}]interface{}{
 				int32(2): int64(2),
-			},
+			}},
 		}
 
 		output, err := c.Marshal(m)
@@ -87,7 +89,9 @@ func Test_marshal_general_struct_of_map(t *testing.T) {
 		var val general.Struct
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Map{
-			int32(2): int64(2),
+			KeyType:     protocol.TypeI32,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{int32(2): int64(2)},
 		}, val[protocol.FieldId(1)])
 	}
 }
@@ -107,7 +111,9 @@ func Test_marshal_struct_of_map(t *testing.T) {
 		var val general.Struct
 		should.NoError(c.Unmarshal(output, &val))
 		should.Equal(general.Map{
-			int32(2): int64(2),
+			KeyType:     protocol.TypeI32,
+			ElementType: protocol.TypeI64,
+			Entries:     map[interface{}]interface{}{int32(2): int64(2)},
 		}, val[protocol.FieldId(1)])
 	}
 }
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/batchcorp/thrift-iterator/client"
+	"github.com/batchcorp/thrift-iterator/middleware"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/proxy"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/server"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+type LoginArgs struct {
+	User  string `thrift:"user,1"`
+	Token string `thrift:"token,2"`
+}
+
+type LoginResult struct {
+	Success *string `thrift:"success,0"`
+}
+
+var requireToken middleware.Interceptor = func(ctx context.Context, call *middleware.Call, next middleware.Handler) (interface{}, error) {
+	var args LoginArgs
+	if err := call.BindArgs(&args); err != nil {
+		return nil, err
+	}
+	if args.Token != "secret" {
+		return nil, protocol.NewApplicationException(protocol.ExceptionUnknown, "unauthorized")
+	}
+	return next(ctx, call)
+}
+
+func newServer(interceptors ...middleware.Interceptor) *server.Server {
+	srv := server.New(server.Config{Interceptors: interceptors})
+	srv.Handle("login", LoginArgs{}, func(ctx context.Context, args interface{}) (interface{}, error) {
+		loginArgs := args.(*LoginArgs)
+		greeting := "welcome " + loginArgs.User + loginArgs.Token
+		return LoginResult{Success: &greeting}, nil
+	})
+	return srv
+}
+
+func pipeDialer(serveConn func(conn net.Conn)) func(ctx context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		clientSide, serverSide := net.Pipe()
+		go serveConn(serverSide)
+		return clientSide, nil
+	}
+}
+
+func Test_chain_order(t *testing.T) {
+	should := require.New(t)
+	var trace []string
+	tracing := func(name string) middleware.Interceptor {
+		return func(ctx context.Context, call *middleware.Call, next middleware.Handler) (interface{}, error) {
+			trace = append(trace, name+" before")
+			result, err := next(ctx, call)
+			trace = append(trace, name+" after")
+			return result, err
+		}
+	}
+	handler := middleware.Wrap(func(ctx context.Context, call *middleware.Call) (interface{}, error) {
+		trace = append(trace, "handler")
+		return call.Header.MessageName, nil
+	}, tracing("a"), middleware.Chain(tracing("b"), tracing("c")))
+	result, err := handler(context.Background(), middleware.NewCall(nil, protocol.MessageHeader{MessageName: "foo"}, nil))
+	should.NoError(err)
+	should.Equal("foo", result)
+	should.Equal([]string{"a before", "b before", "c before", "handler", "c after", "b after", "a after"}, trace)
+}
+
+func Test_server_interceptors(t *testing.T) {
+	should := require.New(t)
+	var logs bytes.Buffer
+	var observed []string
+	srv := newServer(middleware.Logging(log.New(&logs, "", 0)),
+		middleware.Timing(func(method string, elapsed time.Duration, err error) {
+			observed = append(observed, method)
+		}), requireToken)
+	c := client.New(client.Config{Dial: pipeDialer(func(conn net.Conn) { srv.ServeConn(conn) })})
+	var result LoginResult
+	err := c.Call(context.Background(), "login", LoginArgs{User: "bob"}, &result)
+	should.Error(err)
+	should.Contains(err.Error(), "unauthorized")
+	should.NoError(c.Call(context.Background(), "login", LoginArgs{User: "bob", Token: "secret"}, &result))
+	should.Equal("welcome bobsecret", *result.Success)
+	should.Equal([]string{"login", "login"}, observed)
+	should.Contains(logs.String(), "thrift call login seqid 1 failed")
+	should.Contains(logs.String(), "thrift call login seqid 2 done")
+	should.NoError(c.Close())
+	should.NoError(srv.Close())
+}
+
+func Test_client_interceptor_short_circuit(t *testing.T) {
+	should := require.New(t)
+	c := client.New(client.Config{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			return nil, errors.New("should not dial")
+		},
+		Interceptors: []middleware.Interceptor{requireToken},
+	})
+	err := c.Call(context.Background(), "login", LoginArgs{User: "bob"}, nil)
+	should.Error(err)
+	should.Contains(err.Error(), "unauthorized")
+}
+
+func Test_proxy_interceptor_redacts_raw_args(t *testing.T) {
+	should := require.New(t)
+	srv := newServer()
+	redact := func(ctx context.Context, call *middleware.Call, next middleware.Handler) (interface{}, error) {
+		args, err := call.RawArgs()
+		if err != nil {
+			return nil, err
+		}
+		delete(args, protocol.FieldId(2))
+		return next(ctx, call)
+	}
+	upstream := &proxy.Upstream{Dial: pipeDialer(func(conn net.Conn) { srv.ServeConn(conn) })}
+	p := proxy.New(proxy.Config{
+		Route: func(messageName string) (*proxy.Upstream, error) {
+			return upstream, nil
+		},
+		Interceptors: []middleware.Interceptor{redact},
+	})
+	c := client.New(client.Config{Dial: pipeDialer(func(conn net.Conn) { p.ServeConn(conn) })})
+	var result LoginResult
+	should.NoError(c.Call(context.Background(), "login", LoginArgs{User: "bob", Token: "secret"}, &result))
+	should.Equal("welcome bob", *result.Success)
+	call := middleware.NewCall(nil, protocol.MessageHeader{}, &LoginArgs{User: "bob"})
+	args, err := call.RawArgs()
+	should.NoError(err)
+	should.Equal(raw.StructField{Type: protocol.TypeString, Buffer: []byte{0, 0, 0, 3, 'b', 'o', 'b'}},
+		args[protocol.FieldId(1)])
+	should.NoError(c.Close())
+	should.NoError(p.Close())
+	should.NoError(srv.Close())
+}
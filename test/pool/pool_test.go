@@ -0,0 +1,121 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Order struct {
+	Id     int64    `thrift:"id,1"`
+	Paid   bool     `thrift:"paid,2"`
+	Items  []string `thrift:"items,3"`
+	Amount float64  `thrift:"amount,4"`
+}
+
+var order = Order{Id: 1024, Paid: true, Items: []string{"apple", "pear"}, Amount: 9.5}
+
+var apis = []thrifter.API{
+	thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze(),
+	thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze(),
+}
+
+func Test_marshal_to(t *testing.T) {
+	should := require.New(t)
+	for _, api := range apis {
+		expected, err := api.Marshal(order)
+		should.NoError(err)
+		dst := make([]byte, 2, 128)
+		output, err := api.MarshalTo(dst, order)
+		should.NoError(err)
+		should.Equal(append([]byte{0, 0}, expected...), output)
+		should.Equal(&dst[0], &output[0])
+		again, err := api.MarshalTo(output, order)
+		should.NoError(err)
+		should.Equal(append(append([]byte{0, 0}, expected...), expected...), again)
+	}
+}
+
+func Test_borrow_stream(t *testing.T) {
+	should := require.New(t)
+	for _, api := range apis {
+		stream := api.BorrowStream(nil)
+		stream.WriteStructHeader()
+		stream.WriteStructField(protocol.TypeBool, protocol.FieldId(1))
+		// returned in the middle of struct, the state should not leak to next borrower
+		api.ReturnStream(stream)
+		stream = api.BorrowStream(nil)
+		stream.WriteBool(true)
+		should.Equal([]byte{1}, stream.Buffer())
+		api.ReturnStream(stream)
+	}
+}
+
+func Test_borrow_iterator(t *testing.T) {
+	should := require.New(t)
+	for _, api := range apis {
+		input, err := api.Marshal(order)
+		should.NoError(err)
+		iter := api.BorrowIterator(nil, input[:3])
+		iter.ReadStructHeader()
+		iter.ReadStructField()
+		api.ReturnIterator(iter)
+		iter = api.BorrowIterator(nil, input)
+		should.Equal(order, readOrder(iter))
+		should.NoError(iter.Error())
+		api.ReturnIterator(iter)
+	}
+}
+
+func readOrder(iter spi.Iterator) Order {
+	var val Order
+	iter.ReadStructHeader()
+	for {
+		fieldType, fieldId := iter.ReadStructField()
+		if fieldType == protocol.TypeStop {
+			return val
+		}
+		switch fieldId {
+		case 1:
+			val.Id = iter.ReadInt64()
+		case 2:
+			val.Paid = iter.ReadBool()
+		case 3:
+			_, length := iter.ReadListHeader()
+			for i := 0; i < length; i++ {
+				val.Items = append(val.Items, iter.ReadString())
+			}
+		case 4:
+			val.Amount = iter.ReadFloat64()
+		}
+	}
+}
+
+func Benchmark_marshal(b *testing.B) {
+	api := apis[0]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		api.Marshal(order)
+	}
+}
+
+func Benchmark_marshal_to(b *testing.B) {
+	api := apis[0]
+	buf := make([]byte, 0, 128)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = api.MarshalTo(buf[:0], order)
+	}
+}
+
+func Benchmark_unmarshal(b *testing.B) {
+	api := apis[0]
+	input, _ := api.Marshal(order)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var val Order
+		api.Unmarshal(input, &val)
+	}
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_skip_bool_field_before_list_of_bool(t *testing.T) {
+	should := require.New(t)
+	input := []byte{
+		0x11,       // field 1 bool true
+		0x29, 0x21, // field 3 list of 2 bool
+		1, 0,
+		0}
+	iter := compactLE.BorrowIterator(nil, input)
+	defer compactLE.ReturnIterator(iter)
+	iter.ReadStructHeader()
+	fieldType, fieldId := iter.ReadStructField()
+	should.Equal(protocol.TypeBool, fieldType)
+	should.Equal(protocol.FieldId(1), fieldId)
+	should.Equal([]byte{1}, iter.Skip(fieldType, nil))
+	fieldType, _ = iter.ReadStructField()
+	should.Equal(protocol.TypeList, fieldType)
+	elemType, length := iter.ReadListHeader()
+	should.Equal(protocol.TypeBool, elemType)
+	should.Equal(2, length)
+	should.True(iter.ReadBool())
+	should.False(iter.ReadBool())
+	should.NoError(iter.Error())
+	var obj raw.Struct
+	should.NoError(compactLE.Unmarshal(input, &obj))
+	should.Equal([]byte{1}, obj[protocol.FieldId(1)].Buffer)
+	output, err := compactLE.Marshal(obj)
+	should.NoError(err)
+	should.Equal(input, output)
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var compactBE = thrifter.Config{Protocol: thrifter.ProtocolCompact, CompactVersion: protocol.COMPACT_VERSON_BE}.Froze()
+var compactLE = thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze()
+
+var message = general.Message{
+	MessageHeader: protocol.MessageHeader{MessageName: "f", MessageType: protocol.MessageTypeCall, SeqId: 1},
+	Arguments:     general.Struct{protocol.FieldId(1): float64(1.5)},
+}
+
+func Test_read_big_endian_double(t *testing.T) {
+	should := require.New(t)
+	input := []byte{
+		0x82, 0x22, 1, 1, 'f', // version 2, call, seqid 1, name f
+		0x17, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, // field 1 double 1.5 in big endian
+		0}
+	var msg general.Message
+	should.NoError(compactLE.Unmarshal(input, &msg))
+	should.Equal(message, msg)
+	output, err := compactBE.Marshal(message)
+	should.NoError(err)
+	should.Equal(input, output)
+}
+
+func Test_write_version(t *testing.T) {
+	should := require.New(t)
+	output, err := compactLE.Marshal(message)
+	should.NoError(err)
+	should.Equal([]byte{0x82, 0x21, 1, 1, 'f', 0x17, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, 0}, output)
+	var msg general.Message
+	should.NoError(compactBE.Unmarshal(output, &msg))
+	should.Equal(message, msg)
+	converted, err := thrifter.Convert(append([]byte{}, output...), thrifter.ProtocolCompact, thrifter.ProtocolCompact)
+	should.NoError(err)
+	should.Equal(output, converted)
+}
+
+func Test_double_without_message_header(t *testing.T) {
+	should := require.New(t)
+	output, err := compactBE.Marshal(float64(1.5))
+	should.NoError(err)
+	should.Equal([]byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, output)
+	var val float64
+	should.NoError(compactBE.Unmarshal(output, &val))
+	should.Equal(1.5, val)
+}
+
+func Test_reject_unknown_version(t *testing.T) {
+	should := require.New(t)
+	iter := compactLE.NewIterator(nil, []byte{0x82, 0x23, 1, 1, 'f', 0})
+	iter.ReadMessageHeader()
+	should.Error(iter.Error())
+	should.Contains(iter.Error().Error(), "got 03")
+}
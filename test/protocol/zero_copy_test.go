@@ -0,0 +1,153 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Document struct {
+	Title   string   `thrift:"title,1"`
+	Body    []byte   `thrift:"body,2"`
+	Tags    []string `thrift:"tags,3"`
+	Version int32    `thrift:"version,4"`
+}
+
+var document = Document{
+	Title:   "zero copy",
+	Body:    []byte("the body of document"),
+	Tags:    []string{"a", "bb", "ccc"},
+	Version: 3,
+}
+
+func zeroCopyConfigs(zeroCopyString bool) []thrifter.API {
+	return []thrifter.API{
+		thrifter.Config{Protocol: thrifter.ProtocolBinary, ZeroCopy: true, ZeroCopyString: zeroCopyString}.Froze(),
+		thrifter.Config{Protocol: thrifter.ProtocolCompact, ZeroCopy: true, ZeroCopyString: zeroCopyString}.Froze(),
+	}
+}
+
+func Test_zero_copy_binary(t *testing.T) {
+	should := require.New(t)
+	for _, api := range zeroCopyConfigs(false) {
+		input, err := api.Marshal(document)
+		should.NoError(err)
+		var val Document
+		should.NoError(api.Unmarshal(input, &val))
+		should.Equal(document, val)
+		for i := range input {
+			input[i] = 'x'
+		}
+		should.Equal("zero copy", val.Title)
+		should.Equal("xxxxxxxxxxxxxxxxxxxx", string(val.Body))
+	}
+}
+
+func Test_zero_copy_string(t *testing.T) {
+	should := require.New(t)
+	for _, api := range zeroCopyConfigs(true) {
+		input, err := api.Marshal(document)
+		should.NoError(err)
+		var val Document
+		should.NoError(api.Unmarshal(input, &val))
+		should.Equal(document, val)
+		for i := range input {
+			input[i] = 'x'
+		}
+		should.Equal("xxxxxxxxx", val.Title)
+		should.Equal([]string{"x", "xx", "xxx"}, val.Tags)
+	}
+}
+
+func Test_zero_copy_does_not_alias_reader(t *testing.T) {
+	should := require.New(t)
+	api := thrifter.Config{Protocol: thrifter.ProtocolBinary, ZeroCopy: true, ZeroCopyString: true}.Froze()
+	input, err := api.Marshal(document)
+	should.NoError(err)
+	var val Document
+	should.NoError(api.NewDecoder(&readerOf{input}, nil).Decode(&val))
+	for i := range input {
+		input[i] = 'x'
+	}
+	should.Equal(document, val)
+}
+
+type readerOf struct {
+	buf []byte
+}
+
+func (reader *readerOf) Read(p []byte) (int, error) {
+	n := copy(p, reader.buf)
+	reader.buf = reader.buf[n:]
+	return n, nil
+}
+
+func benchmarkUnmarshal(b *testing.B, cfg thrifter.Config) {
+	api := cfg.Froze()
+	input, _ := api.Marshal(document)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var val Document
+		api.Unmarshal(input, &val)
+	}
+}
+
+func Benchmark_reflection_copy(b *testing.B) {
+	benchmarkUnmarshal(b, thrifter.Config{Protocol: thrifter.ProtocolBinary})
+}
+
+func Benchmark_reflection_zero_copy(b *testing.B) {
+	benchmarkUnmarshal(b, thrifter.Config{Protocol: thrifter.ProtocolBinary, ZeroCopy: true, ZeroCopyString: true})
+}
+
+// decodeDocument is hand written, it calls the iterator the same way as the decoder generated by static codegen,
+// so that the iterator is benchmarked without the plugin compiled by static codegen
+func decodeDocument(val *Document, iter spi.Iterator) {
+	iter.ReadStructHeader()
+	for {
+		fieldType, fieldId := iter.ReadStructField()
+		if fieldType == protocol.TypeStop {
+			return
+		}
+		switch fieldId {
+		case 1:
+			val.Title = iter.ReadString()
+		case 2:
+			val.Body = iter.ReadBinary()
+		case 3:
+			_, length := iter.ReadListHeader()
+			val.Tags = make([]string, length)
+			for i := 0; i < length; i++ {
+				val.Tags[i] = iter.ReadString()
+			}
+		case 4:
+			val.Version = iter.ReadInt32()
+		default:
+			iter.Discard(fieldType)
+		}
+	}
+}
+
+func benchmarkIterator(b *testing.B, cfg thrifter.Config) {
+	api := cfg.Froze()
+	input, _ := api.Marshal(document)
+	iter := api.NewIterator(nil, input)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iter.Reset(nil, input)
+		var val Document
+		decodeDocument(&val, iter)
+	}
+}
+
+func Benchmark_iterator_copy(b *testing.B) {
+	benchmarkIterator(b, thrifter.Config{Protocol: thrifter.ProtocolBinary})
+}
+
+func Benchmark_iterator_zero_copy(b *testing.B) {
+	benchmarkIterator(b, thrifter.Config{Protocol: thrifter.ProtocolBinary, ZeroCopy: true, ZeroCopyString: true})
+}
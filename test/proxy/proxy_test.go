@@ -0,0 +1,265 @@
+package test
+
+import (
+	"context"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/client"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/proxy"
+	"github.com/batchcorp/thrift-iterator/raw"
+	"github.com/batchcorp/thrift-iterator/server"
+	"github.com/batchcorp/thrift-iterator/transport"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+type EchoArgs struct {
+	Message string `thrift:"message,1"`
+	Secret  string `thrift:"secret,2"`
+	Flag    bool   `thrift:"flag,3"`
+	Tags    []int8 `thrift:"tags,4"`
+}
+
+type EchoResult struct {
+	Success *EchoArgs `thrift:"success,0"`
+}
+
+type side struct {
+	protocol thrifter.Protocol
+	framed   bool
+}
+
+var sides = []side{
+	{thrifter.ProtocolBinary, false},
+	{thrifter.ProtocolBinary, true},
+	{thrifter.ProtocolCompact, false},
+	{thrifter.ProtocolCompact, true},
+}
+
+func newUpstream(s side) *proxy.Upstream {
+	srv := server.New(server.Config{API: thrifter.Config{Protocol: s.protocol}.Froze(), Framed: s.framed})
+	srv.Handle("echo", EchoArgs{}, func(ctx context.Context, args interface{}) (interface{}, error) {
+		return EchoResult{Success: args.(*EchoArgs)}, nil
+	})
+	return &proxy.Upstream{
+		Protocol: s.protocol,
+		Framed:   s.framed,
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			clientSide, serverSide := net.Pipe()
+			go srv.ServeConn(serverSide)
+			return clientSide, nil
+		},
+	}
+}
+
+func Test_proxy(t *testing.T) {
+	should := require.New(t)
+	for _, downstream := range sides {
+		for _, upstreamSide := range sides {
+			upstream := newUpstream(upstreamSide)
+			p := proxy.New(proxy.Config{
+				Protocol: downstream.protocol,
+				Framed:   downstream.framed,
+				Route: func(messageName string) (*proxy.Upstream, error) {
+					if messageName == "echo" || messageName == "legacy_echo" {
+						return upstream, nil
+					}
+					return nil, nil
+				},
+				Rewrite: func(header *protocol.MessageHeader, args raw.Struct) error {
+					header.MessageName = "echo"
+					delete(args, protocol.FieldId(2))
+					return nil
+				},
+			})
+			api := thrifter.Config{Protocol: downstream.protocol}.Froze()
+			c := client.New(client.Config{API: api, Framed: downstream.framed,
+				Dial: func(ctx context.Context) (net.Conn, error) {
+					clientSide, proxySide := net.Pipe()
+					go p.ServeConn(proxySide)
+					return clientSide, nil
+				}})
+			args := EchoArgs{Message: "hello", Secret: "password", Flag: true, Tags: []int8{1, 2}}
+			var result EchoResult
+			should.NoError(c.Call(context.Background(), "echo", args, &result))
+			should.Equal(EchoArgs{Message: "hello", Flag: true, Tags: []int8{1, 2}}, *result.Success)
+			err := c.Call(context.Background(), "unknown", args, &result)
+			should.Error(err)
+			should.Contains(err.Error(), "UNKNOWN_METHOD")
+			should.NoError(c.Close())
+			should.NoError(p.Close())
+		}
+	}
+}
+
+func Test_proxy_restores_seqid(t *testing.T) {
+	should := require.New(t)
+	upstream := newUpstream(sides[3])
+	p := proxy.New(proxy.Config{
+		Route: func(messageName string) (*proxy.Upstream, error) {
+			return upstream, nil
+		},
+	})
+	clientSide, proxySide := net.Pipe()
+	go p.ServeConn(proxySide)
+	conn := transport.NewConn(nil, clientSide, false)
+	for _, seqId := range []protocol.SeqId{100, 7, 100} {
+		should.NoError(conn.WriteMessage(protocol.MessageHeader{
+			MessageName: "echo", MessageType: protocol.MessageTypeCall, SeqId: seqId}, EchoArgs{Message: "hi"}))
+		header, err := conn.ReadMessageHeader()
+		should.NoError(err)
+		should.Equal(protocol.MessageHeader{
+			MessageName: "echo", MessageType: protocol.MessageTypeReply, SeqId: seqId}, header)
+		var result EchoResult
+		should.NoError(conn.ReadMessageBody(&result))
+		should.Equal("hi", result.Success.Message)
+	}
+	conn.Close()
+	should.NoError(p.Close())
+}
+
+type ReorderedArgs struct {
+	Flag    bool   `thrift:"flag,3"`
+	Message string `thrift:"message,1"`
+	Secret  string `thrift:"secret,2"`
+}
+
+// newRecorder is an upstream sending the arguments received to bodies, and replying nothing if reply is false
+func newRecorder(s side, bodies chan []byte, reply bool) *proxy.Upstream {
+	api := thrifter.Config{Protocol: s.protocol}.Froze()
+	return &proxy.Upstream{
+		Protocol: s.protocol,
+		Framed:   s.framed,
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			clientSide, serverSide := net.Pipe()
+			go func() {
+				conn := transport.NewConn(api, serverSide, s.framed)
+				defer conn.Close()
+				for {
+					header, err := conn.ReadMessageHeader()
+					if err != nil {
+						return
+					}
+					body, err := conn.SkipMessageBody()
+					if err != nil {
+						return
+					}
+					bodies <- body
+					if reply {
+						header.MessageType = protocol.MessageTypeReply
+						conn.WriteMessage(header, EchoResult{})
+					}
+				}
+			}()
+			return clientSide, nil
+		},
+	}
+}
+
+func Test_proxy_keeps_field_order(t *testing.T) {
+	should := require.New(t)
+	for _, downstream := range sides {
+		for _, upstreamSide := range sides {
+			bodies := make(chan []byte, 1)
+			upstream := newRecorder(upstreamSide, bodies, true)
+			p := proxy.New(proxy.Config{
+				Protocol: downstream.protocol,
+				Framed:   downstream.framed,
+				Route: func(messageName string) (*proxy.Upstream, error) {
+					return upstream, nil
+				},
+				Rewrite: func(header *protocol.MessageHeader, args raw.Struct) error {
+					if header.MessageName == "rewrite" {
+						delete(args, protocol.FieldId(1))
+						args[protocol.FieldId(9)] = args[protocol.FieldId(2)]
+					}
+					return nil
+				},
+			})
+			c := client.New(client.Config{API: thrifter.Config{Protocol: downstream.protocol}.Froze(),
+				Framed: downstream.framed,
+				Dial: func(ctx context.Context) (net.Conn, error) {
+					clientSide, proxySide := net.Pipe()
+					go p.ServeConn(proxySide)
+					return clientSide, nil
+				}})
+			upstreamAPI := thrifter.Config{Protocol: upstreamSide.protocol}.Froze()
+			args := ReorderedArgs{Flag: true, Message: "hello", Secret: "password"}
+			expected, err := upstreamAPI.Marshal(args)
+			should.NoError(err)
+			var result EchoResult
+			should.NoError(c.Call(context.Background(), "keep", args, &result))
+			should.Equal(expected, <-bodies)
+			expected, err = upstreamAPI.Marshal(struct {
+				Flag    bool   `thrift:"flag,3"`
+				Secret  string `thrift:"secret,2"`
+				Renamed string `thrift:"renamed,9"`
+			}{true, "password", "password"})
+			should.NoError(err)
+			should.NoError(c.Call(context.Background(), "rewrite", args, &result))
+			should.Equal(expected, <-bodies)
+			should.NoError(c.Close())
+			should.NoError(p.Close())
+		}
+	}
+}
+
+func Test_proxy_call_timeout(t *testing.T) {
+	should := require.New(t)
+	upstream := newRecorder(sides[0], make(chan []byte, 1), false)
+	p := proxy.New(proxy.Config{
+		Route: func(messageName string) (*proxy.Upstream, error) {
+			return upstream, nil
+		},
+		CallTimeout: 50 * time.Millisecond,
+	})
+	c := client.New(client.Config{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			clientSide, proxySide := net.Pipe()
+			go p.ServeConn(proxySide)
+			return clientSide, nil
+		}})
+	var result EchoResult
+	start := time.Now()
+	err := c.Call(context.Background(), "echo", EchoArgs{Message: "hi"}, &result)
+	should.Error(err)
+	should.Contains(err.Error(), "INTERNAL_ERROR")
+	should.Contains(err.Error(), context.DeadlineExceeded.Error())
+	should.Less(int64(time.Since(start)), int64(5*time.Second))
+	should.NoError(c.Close())
+	should.NoError(p.Close())
+}
+
+func Test_proxy_reuses_upstream_conn_by_addr(t *testing.T) {
+	should := require.New(t)
+	upstream := newUpstream(sides[0])
+	dials := 0
+	p := proxy.New(proxy.Config{
+		Route: func(messageName string) (*proxy.Upstream, error) {
+			// a new *Upstream each call, still the same upstream
+			return &proxy.Upstream{
+				Addr: "echo",
+				Dial: func(ctx context.Context) (net.Conn, error) {
+					dials++
+					return upstream.Dial(ctx)
+				},
+			}, nil
+		},
+	})
+	c := client.New(client.Config{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			clientSide, proxySide := net.Pipe()
+			go p.ServeConn(proxySide)
+			return clientSide, nil
+		}})
+	for i := 0; i < 3; i++ {
+		var result EchoResult
+		should.NoError(c.Call(context.Background(), "echo", EchoArgs{Message: "hi"}, &result))
+		should.Equal("hi", result.Success.Message)
+	}
+	should.Equal(1, dials)
+	should.NoError(c.Close())
+	should.NoError(p.Close())
+}
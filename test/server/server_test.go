@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/general"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/server"
+	"github.com/batchcorp/thrift-iterator/transport"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+type AddArgs struct {
+	A int32 `thrift:"a,1"`
+	B int32 `thrift:"b,2"`
+}
+
+type AddResult struct {
+	Success *int32 `thrift:"success,0"`
+}
+
+type NotifyArgs struct {
+	Event string `thrift:"event,1"`
+}
+
+var configs = []server.Config{
+	{API: thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze()},
+	{API: thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze(), Framed: true},
+	{API: thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze()},
+	{API: thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze(), Framed: true},
+}
+
+func newServer(cfg server.Config, notified chan string) *server.Server {
+	srv := server.New(cfg)
+	srv.Handle("add", AddArgs{}, func(ctx context.Context, args interface{}) (interface{}, error) {
+		addArgs := args.(*AddArgs)
+		if addArgs.A < 0 {
+			return nil, errors.New("negative")
+		}
+		sum := addArgs.A + addArgs.B
+		return AddResult{Success: &sum}, nil
+	})
+	srv.HandleOneway("notify", (*NotifyArgs)(nil), func(ctx context.Context, args interface{}) (interface{}, error) {
+		notified <- args.(*NotifyArgs).Event
+		return nil, nil
+	})
+	return srv
+}
+
+func call(conn *transport.Conn, method string, seqId protocol.SeqId, args interface{}) (protocol.MessageHeader, general.Struct, error) {
+	err := conn.WriteMessage(protocol.MessageHeader{
+		MessageName: method, MessageType: protocol.MessageTypeCall, SeqId: seqId}, args)
+	if err != nil {
+		return protocol.MessageHeader{}, nil, err
+	}
+	header, err := conn.ReadMessageHeader()
+	if err != nil {
+		return header, nil, err
+	}
+	var result general.Struct
+	err = conn.ReadMessageBody(&result)
+	return header, result, err
+}
+
+func Test_serve_pipe(t *testing.T) {
+	should := require.New(t)
+	for _, cfg := range configs {
+		notified := make(chan string, 1)
+		srv := newServer(cfg, notified)
+		clientSide, serverSide := net.Pipe()
+		go srv.ServeConn(serverSide)
+		conn := transport.NewConn(cfg.API, clientSide, cfg.Framed)
+		header, result, err := call(conn, "add", 7, AddArgs{A: 1, B: 2})
+		should.NoError(err)
+		should.Equal(protocol.MessageHeader{MessageName: "add", MessageType: protocol.MessageTypeReply, SeqId: 7}, header)
+		should.Equal(general.Struct{protocol.FieldId(0): int32(3)}, result)
+		should.NoError(conn.WriteMessage(protocol.MessageHeader{
+			MessageName: "notify", MessageType: protocol.MessageTypeOneWay, SeqId: 8}, NotifyArgs{Event: "hello"}))
+		should.Equal("hello", <-notified)
+		header, result, err = call(conn, "add", 9, AddArgs{A: -1})
+		should.NoError(err)
+		should.Equal(protocol.MessageTypeException, header.MessageType)
+		should.Equal(protocol.SeqId(9), header.SeqId)
+		should.Equal("negative", result[protocol.FieldId(1)])
+		header, result, err = call(conn, "subtract", 10, AddArgs{})
+		should.NoError(err)
+		should.Equal(protocol.MessageTypeException, header.MessageType)
+		should.Equal(int32(1), result[protocol.FieldId(2)])
+		should.NoError(srv.Close())
+		conn.Close()
+	}
+}
+
+func Test_serve_listener_and_shutdown(t *testing.T) {
+	should := require.New(t)
+	for _, cfg := range configs {
+		srv := newServer(cfg, make(chan string, 1))
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		should.NoError(err)
+		served := make(chan error, 1)
+		go func() {
+			served <- srv.Serve(listener)
+		}()
+		netConn, err := net.Dial("tcp", listener.Addr().String())
+		should.NoError(err)
+		conn := transport.NewConn(cfg.API, netConn, cfg.Framed)
+		_, result, err := call(conn, "add", 1, AddArgs{A: 40, B: 2})
+		should.NoError(err)
+		should.Equal(general.Struct{protocol.FieldId(0): int32(42)}, result)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		should.NoError(srv.Shutdown(ctx))
+		cancel()
+		should.Equal(server.ErrServerClosed, <-served)
+		_, err = conn.ReadMessageHeader()
+		should.Error(err)
+		conn.Close()
+	}
+}
+
+func Test_handler_panic_replied_as_internal_error(t *testing.T) {
+	should := require.New(t)
+	for _, cfg := range configs {
+		errorLog := &bytes.Buffer{}
+		cfg.ErrorLog = log.New(errorLog, "", 0)
+		srv := newServer(cfg, make(chan string, 1))
+		srv.Handle("divide", AddArgs{}, func(ctx context.Context, args interface{}) (interface{}, error) {
+			divideArgs := args.(*AddArgs)
+			quotient := divideArgs.A / divideArgs.B
+			return AddResult{Success: &quotient}, nil
+		})
+		clientSide, serverSide := net.Pipe()
+		go srv.ServeConn(serverSide)
+		conn := transport.NewConn(cfg.API, clientSide, cfg.Framed)
+		header, result, err := call(conn, "divide", 1, AddArgs{A: 1})
+		should.NoError(err)
+		should.Equal(protocol.MessageTypeException, header.MessageType)
+		should.Equal(int32(protocol.ExceptionInternalError), result[protocol.FieldId(2)])
+		should.Contains(result[protocol.FieldId(1)], "divide by zero")
+		should.Contains(errorLog.String(), "thrift call divide seqid 1 panic")
+		_, result, err = call(conn, "add", 2, AddArgs{A: 1, B: 2})
+		should.NoError(err)
+		should.Equal(general.Struct{protocol.FieldId(0): int32(3)}, result)
+		should.NoError(srv.Close())
+		conn.Close()
+	}
+}
+
+func Test_oneway_method_called_as_call(t *testing.T) {
+	should := require.New(t)
+	for _, cfg := range configs {
+		notified := make(chan string, 1)
+		srv := newServer(cfg, notified)
+		clientSide, serverSide := net.Pipe()
+		go srv.ServeConn(serverSide)
+		conn := transport.NewConn(cfg.API, clientSide, cfg.Framed)
+		header, result, err := call(conn, "notify", 1, NotifyArgs{Event: "hello"})
+		should.NoError(err)
+		should.Equal(protocol.MessageTypeException, header.MessageType)
+		should.Equal(protocol.SeqId(1), header.SeqId)
+		should.Equal(int32(protocol.ExceptionInvalidMessageType), result[protocol.FieldId(2)])
+		should.Len(notified, 0)
+		_, result, err = call(conn, "add", 2, AddArgs{A: 1, B: 2})
+		should.NoError(err)
+		should.Equal(general.Struct{protocol.FieldId(0): int32(3)}, result)
+		should.NoError(srv.Close())
+		conn.Close()
+	}
+}
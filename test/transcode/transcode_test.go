@@ -0,0 +1,99 @@
+package test
+
+import (
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type Inner struct {
+	Flag  bool   `thrift:"flag,1"`
+	Bytes []byte `thrift:"bytes,2"`
+}
+
+type Args struct {
+	Name   string           `thrift:"name,3"`
+	Flag   bool             `thrift:"flag,1"`
+	Off    bool             `thrift:"off,2"`
+	Score  float64          `thrift:"score,20"`
+	Small  int8             `thrift:"small,4"`
+	Big    int64            `thrift:"big,5"`
+	Flags  []bool           `thrift:"flags,6"`
+	Inners []Inner          `thrift:"inners,7"`
+	Counts map[string]int16 `thrift:"counts,8"`
+	Inner  Inner            `thrift:"inner,10"`
+}
+
+var args = Args{
+	Name:   "hello",
+	Flag:   true,
+	Score:  1.5,
+	Small:  -1,
+	Big:    1 << 40,
+	Flags:  []bool{true, false, true},
+	Inners: []Inner{{Flag: true, Bytes: []byte{1, 2}}, {}},
+	Counts: map[string]int16{"a": 1},
+	Inner:  Inner{Flag: false, Bytes: []byte("x")},
+}
+
+func message(api thrifter.API) []byte {
+	stream := api.NewStream(nil, nil)
+	stream.WriteMessageHeader(protocol.MessageHeader{
+		MessageName: "call", MessageType: protocol.MessageTypeCall, SeqId: 17})
+	body, err := api.Marshal(args)
+	if err != nil {
+		panic(err)
+	}
+	return append(stream.Buffer(), body...)
+}
+
+func Test_convert(t *testing.T) {
+	should := require.New(t)
+	protocols := []thrifter.Protocol{thrifter.ProtocolBinary, thrifter.ProtocolCompact}
+	for _, from := range protocols {
+		for _, to := range protocols {
+			fromAPI := thrifter.Config{Protocol: from}.Froze()
+			toAPI := thrifter.Config{Protocol: to}.Froze()
+			output, err := thrifter.Convert(message(fromAPI), from, to)
+			should.NoError(err)
+			should.Equal(message(toAPI), output)
+		}
+	}
+}
+
+func Test_transcode_value(t *testing.T) {
+	should := require.New(t)
+	binaryAPI := thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze()
+	compactAPI := thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze()
+	input, err := binaryAPI.Marshal([]int64{1, -2, 3})
+	should.NoError(err)
+	stream := compactAPI.NewStream(nil, nil)
+	should.NoError(thrifter.TranscodeValue(binaryAPI.NewIterator(nil, input), stream, protocol.TypeList))
+	var val []int64
+	should.NoError(compactAPI.Unmarshal(stream.Buffer(), &val))
+	should.Equal([]int64{1, -2, 3}, val)
+}
+
+func Test_convert_empty_map(t *testing.T) {
+	should := require.New(t)
+	compactAPI := thrifter.Config{Protocol: thrifter.ProtocolCompact}.Froze()
+	stream := compactAPI.NewStream(nil, nil)
+	stream.WriteMessageHeader(protocol.MessageHeader{MessageName: "call", MessageType: protocol.MessageTypeCall})
+	body, err := compactAPI.Marshal(struct {
+		Empty map[int32]bool `thrift:"empty,1"`
+	}{map[int32]bool{}})
+	should.NoError(err)
+	output, err := thrifter.Convert(append(stream.Buffer(), body...), thrifter.ProtocolCompact, thrifter.ProtocolBinary)
+	should.NoError(err)
+	msg, err := thrifter.UnmarshalMessage(output)
+	should.NoError(err)
+	should.Equal(1, len(msg.Arguments))
+}
+
+func Test_convert_unsupported_protocol(t *testing.T) {
+	should := require.New(t)
+	input := message(thrifter.Config{Protocol: thrifter.ProtocolBinary}.Froze())
+	_, err := thrifter.Convert(input, thrifter.ProtocolBinary, thrifter.Protocol(7))
+	should.Error(err)
+}
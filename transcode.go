@@ -0,0 +1,95 @@
+package thrifter
+
+import (
+	"fmt"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"github.com/batchcorp/thrift-iterator/spi"
+)
+
+var protocolAPIs = map[Protocol]API{
+	ProtocolBinary:  Config{Protocol: ProtocolBinary}.Froze(),
+	ProtocolCompact: Config{Protocol: ProtocolCompact}.Froze(),
+}
+
+// Transcode reads one message from src and writes it to dst token by token,
+// the types and the field order are kept as they are, the stream is not flushed.
+// Compact protocol does not encode the element types of empty map, they are written as stop to binary protocol
+func Transcode(src spi.Iterator, dst spi.Stream) error {
+	dst.WriteMessageHeader(src.ReadMessageHeader())
+	return TranscodeValue(src, dst, protocol.TypeStruct)
+}
+
+// TranscodeValue reads one value of ttype from src and writes it to dst
+func TranscodeValue(src spi.Iterator, dst spi.Stream, ttype protocol.TType) error {
+	transcode(src, dst, ttype)
+	if src.Error() != nil {
+		return src.Error()
+	}
+	return dst.Error()
+}
+
+func transcode(src spi.Iterator, dst spi.Stream, ttype protocol.TType) {
+	if src.Error() != nil {
+		return
+	}
+	switch ttype {
+	case protocol.TypeBool:
+		dst.WriteBool(src.ReadBool())
+	case protocol.TypeI08:
+		dst.WriteInt8(src.ReadInt8())
+	case protocol.TypeI16:
+		dst.WriteInt16(src.ReadInt16())
+	case protocol.TypeI32:
+		dst.WriteInt32(src.ReadInt32())
+	case protocol.TypeI64:
+		dst.WriteInt64(src.ReadInt64())
+	case protocol.TypeDouble:
+		dst.WriteFloat64(src.ReadFloat64())
+	case protocol.TypeString:
+		dst.WriteBinary(src.ReadBinary())
+	case protocol.TypeList, protocol.TypeSet:
+		spi.EnterContainer(src)
+		elemType, size := src.ReadListHeader()
+		dst.WriteListHeader(elemType, size)
+		for i := 0; i < size; i++ {
+			transcode(src, dst, elemType)
+		}
+		spi.LeaveContainer(src)
+	case protocol.TypeMap:
+		spi.EnterContainer(src)
+		keyType, elemType, size := src.ReadMapHeader()
+		dst.WriteMapHeader(keyType, elemType, size)
+		for i := 0; i < size; i++ {
+			transcode(src, dst, keyType)
+			transcode(src, dst, elemType)
+		}
+		spi.LeaveContainer(src)
+	case protocol.TypeStruct:
+		src.ReadStructHeader()
+		dst.WriteStructHeader()
+		for {
+			fieldType, fieldId := src.ReadStructField()
+			if fieldType == protocol.TypeStop || src.Error() != nil {
+				break
+			}
+			dst.WriteStructField(fieldType, fieldId)
+			transcode(src, dst, fieldType)
+		}
+		dst.WriteStructFieldStop()
+	default:
+		src.ReportError("Transcode", fmt.Sprintf("unsupported type: %v", ttype))
+	}
+}
+
+// Convert changes the protocol of the message encoded in buf
+func Convert(buf []byte, from Protocol, to Protocol) ([]byte, error) {
+	fromAPI, toAPI := protocolAPIs[from], protocolAPIs[to]
+	if fromAPI == nil || toAPI == nil {
+		return nil, fmt.Errorf("unsupported protocol conversion: %d to %d", from, to)
+	}
+	stream := toAPI.NewStream(nil, nil)
+	if err := Transcode(fromAPI.NewIterator(nil, buf), stream); err != nil {
+		return nil, err
+	}
+	return stream.Buffer(), nil
+}
@@ -0,0 +1,154 @@
+// Package transport reads and writes thrift messages over a connection,
+// it is shared by the server, client and proxy packages
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/batchcorp/thrift-iterator"
+	"github.com/batchcorp/thrift-iterator/protocol"
+	"io"
+	"time"
+)
+
+// Conn reads message by ReadMessageHeader followed by ReadMessageBody,
+// and writes message by WriteMessage. Reading should be done by one goroutine,
+// writing is safe to be done concurrently. The framed connection reads and writes the frames
+// like thrifter.Decoder and thrifter.Encoder, the frame larger than thrifter.MaxFrameSize is rejected
+type Conn struct {
+	api     thrifter.API
+	conn    io.ReadWriteCloser
+	framed  bool
+	reader  *bufio.Reader
+	frames  *thrifter.FrameReader
+	decoder *thrifter.Decoder
+	// writeLock is held by the goroutine writing, a channel so that waiting for it can be given up
+	writeLock chan struct{}
+	encoder   *thrifter.Encoder
+}
+
+// NewConn uses thrifter.DefaultConfig if api is nil
+func NewConn(api thrifter.API, conn io.ReadWriteCloser, framed bool) *Conn {
+	if api == nil {
+		api = thrifter.DefaultConfig
+	}
+	reader := bufio.NewReader(conn)
+	return &Conn{
+		api:       api,
+		conn:      conn,
+		framed:    framed,
+		reader:    reader,
+		frames:    thrifter.NewFrameReader(reader, false),
+		decoder:   api.NewDecoder(reader, nil),
+		encoder:   api.NewEncoder(nil),
+		writeLock: make(chan struct{}, 1),
+	}
+}
+
+// ReadMessageHeader blocks until next message arrives
+func (conn *Conn) ReadMessageHeader() (protocol.MessageHeader, error) {
+	if conn.framed {
+		if err := conn.readFrame(); err != nil {
+			return protocol.MessageHeader{}, err
+		}
+	}
+	return conn.decoder.DecodeMessageHeader()
+}
+
+// ReadMessageBody decodes the arguments or result struct following the message header
+func (conn *Conn) ReadMessageBody(val interface{}) error {
+	return conn.decoder.Decode(val)
+}
+
+// SkipMessageBody reads the arguments or result struct following the message header as encoded bytes
+func (conn *Conn) SkipMessageBody() ([]byte, error) {
+	return conn.decoder.Skip(protocol.TypeStruct)
+}
+
+func (conn *Conn) readFrame() error {
+	frame, err := conn.frames.Next()
+	if err != nil {
+		return err
+	}
+	// decode through a reader, so that no decoded value points into the frame buffer reused by next frame
+	conn.decoder.Reset(bytes.NewReader(frame), nil)
+	return nil
+}
+
+// WriteMessage sends the message header and the message body as a whole
+func (conn *Conn) WriteMessage(header protocol.MessageHeader, body interface{}) error {
+	conn.writeLock <- struct{}{}
+	defer conn.unlockWrite()
+	return conn.writeMessage(header, body)
+}
+
+// WriteMessageContext is WriteMessage giving up when ctx is done. If ctx is done before writing,
+// ctx.Err() is returned and conn can still be used. Writing interrupted by ctx returns the io error
+// and leaves conn broken, the underlying connection needs SetWriteDeadline to be interrupted
+func (conn *Conn) WriteMessageContext(ctx context.Context, header protocol.MessageHeader, body interface{}) error {
+	select {
+	case conn.writeLock <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer conn.unlockWrite()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadliner, ok := conn.conn.(writeDeadliner); ok {
+		stop, err := watchContext(ctx, deadliner.SetWriteDeadline)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	return conn.writeMessage(header, body)
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+func (conn *Conn) unlockWrite() {
+	<-conn.writeLock
+}
+
+func (conn *Conn) writeMessage(header protocol.MessageHeader, body interface{}) error {
+	conn.encoder.Reset(nil)
+	if err := conn.encoder.EncodeMessageHeader(header); err != nil {
+		return err
+	}
+	if err := conn.encoder.Encode(body); err != nil {
+		return err
+	}
+	return conn.write(conn.encoder.Buffer())
+}
+
+// WriteEncodedMessage sends the message header and the message body already encoded by the protocol of conn
+func (conn *Conn) WriteEncodedMessage(header protocol.MessageHeader, body []byte) error {
+	conn.writeLock <- struct{}{}
+	defer conn.unlockWrite()
+	conn.encoder.Reset(nil)
+	if err := conn.encoder.EncodeMessageHeader(header); err != nil {
+		return err
+	}
+	return conn.write(append(conn.encoder.Buffer(), body...))
+}
+
+func (conn *Conn) write(buf []byte) error {
+	if conn.framed {
+		return thrifter.WriteFrame(conn.conn, thrifter.Format{Framed: true}, 0, buf)
+	}
+	_, err := conn.conn.Write(buf)
+	return err
+}
+
+// WriteException replies the exception to request
+func (conn *Conn) WriteException(request protocol.MessageHeader, exception *protocol.ApplicationException) error {
+	return conn.WriteMessage(protocol.ExceptionHeader(request), exception)
+}
+
+func (conn *Conn) Close() error {
+	return conn.conn.Close()
+}
@@ -0,0 +1,40 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// aLongTimeAgo is used as deadline to interrupt blocking read and write when ctx is done
+var aLongTimeAgo = time.Unix(1, 0)
+
+// WatchContext clears the deadline of conn, and sets it in the past once ctx is done,
+// so that the blocking read and write are interrupted. stop must be called before conn is used with another ctx.
+// ctx deadline is watched by ctx.Done() as well, the io error after ctx is done should be reported as ctx.Err()
+func WatchContext(ctx context.Context, conn net.Conn) (stop func(), err error) {
+	return watchContext(ctx, conn.SetDeadline)
+}
+
+func watchContext(ctx context.Context, setDeadline func(time.Time) error) (func(), error) {
+	if err := setDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return func() {}, nil
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			setDeadline(aLongTimeAgo)
+		case <-stop:
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}, nil
+}